@@ -0,0 +1,29 @@
+package ghsearch
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrAPIKeyNotFound indicates API key is unknown or revoked.
+var ErrAPIKeyNotFound = errors.New("api key not found")
+
+// APIKey represents a consumer of the service identified by its key.
+type APIKey struct {
+	// ID identifies the consumer on logs, metrics and usage, the key
+	// itself is a secret and never exposed.
+	ID string `json:"id"`
+
+	// Scopes are endpoint groups the key can access.
+	Scopes []string `json:"scopes"`
+
+	// Rate and Burst override default client rate limit when set.
+	Rate  float64 `json:"rate,omitempty"`
+	Burst int     `json:"burst,omitempty"`
+}
+
+// APIKeyStore provides API key lookup.
+type APIKeyStore interface {
+	// APIKey returns API key details, ErrAPIKeyNotFound when its unknown.
+	APIKey(ctx context.Context, key string) (*APIKey, error)
+}
@@ -0,0 +1,257 @@
+package ghsearch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// CircuitState represents circuit breaker state.
+type CircuitState int
+
+// Circuit breaker states.
+const (
+	// CircuitClosed lets all requests through while counting failures.
+	CircuitClosed CircuitState = iota
+	// CircuitOpen fails requests immediately until cool down ends.
+	CircuitOpen
+	// CircuitHalfOpen lets limited requests through to probe recovery.
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+func (s CircuitState) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+func (s *CircuitState) UnmarshalText(b []byte) error {
+	switch string(b) {
+	case "closed":
+		*s = CircuitClosed
+	case "open":
+		*s = CircuitOpen
+	case "half-open":
+		*s = CircuitHalfOpen
+	default:
+		return fmt.Errorf("unknown circuit state: %s", b)
+	}
+	return nil
+}
+
+// CircuitBreakerConfig represents circuit breaker thresholds.
+type CircuitBreakerConfig struct {
+	// FailureRatio opens the circuit when reached within the window.
+	FailureRatio float64
+	// MinRequests within the window before failure ratio is considered.
+	MinRequests int
+	// Window resets failure counts periodically while closed.
+	Window time.Duration
+	// CoolDown keeps the circuit open before probing recovery.
+	CoolDown time.Duration
+	// HalfOpenRequests allowed concurrently to probe recovery.
+	HalfOpenRequests int
+}
+
+// DefaultCircuitBreakerConfig opens circuit when half of the requests failed.
+var DefaultCircuitBreakerConfig = CircuitBreakerConfig{
+	FailureRatio:     0.5,
+	MinRequests:      10,
+	Window:           30 * time.Second,
+	CoolDown:         15 * time.Second,
+	HalfOpenRequests: 1,
+}
+
+// CircuitStatus represents circuit breaker state details.
+type CircuitStatus struct {
+	State     CircuitState `json:"state"`
+	Successes int          `json:"successes"`
+	Failures  int          `json:"failures"`
+	OpenedAt  *time.Time   `json:"opened_at,omitempty"`
+}
+
+// CircuitBreaker represents user source decorator that fails fast when
+// the source keeps failing, giving it time to recover.
+type CircuitBreaker struct {
+	source UserSource
+	conf   CircuitBreakerConfig
+
+	mu          sync.Mutex
+	state       CircuitState
+	successes   int
+	failures    int
+	windowStart time.Time
+	openedAt    time.Time
+	probes      int
+
+	// generation changes on every state change so results of requests
+	// admitted in a previous state are not counted on the current one.
+	generation uint64
+}
+
+// ticket represents circuit state generation a request was admitted in.
+type ticket struct {
+	generation uint64
+}
+
+// User returns user details from the source when circuit allows.
+func (cb *CircuitBreaker) User(ctx context.Context, username string) (*User, error) {
+	t, err := cb.allow()
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := cb.source.User(ctx, username)
+	cb.record(ctx, t, err)
+	return user, err
+}
+
+// Users returns users details from the source when circuit allows. A batch
+// source call counts as a single request, otherwise each user is counted.
+func (cb *CircuitBreaker) Users(ctx context.Context, usernames []string) ([]UserResult, error) {
+	bs, ok := cb.source.(BatchUserSource)
+	if !ok {
+		return SourceUsers(ctx, UserSourceFunc(cb.User), usernames), nil
+	}
+	t, err := cb.allow()
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := bs.Users(ctx, usernames)
+	cb.record(ctx, t, err)
+	return results, err
+}
+
+// Status returns current circuit breaker state details.
+func (cb *CircuitBreaker) Status() CircuitStatus {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	cb.checkCoolDown(time.Now())
+	s := CircuitStatus{
+		State:     cb.state,
+		Successes: cb.successes,
+		Failures:  cb.failures,
+	}
+	if cb.state != CircuitClosed {
+		t := cb.openedAt
+		s.OpenedAt = &t
+	}
+	return s
+}
+
+func (cb *CircuitBreaker) allow() (ticket, error) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	cb.checkCoolDown(time.Now())
+	t := ticket{cb.generation}
+	switch cb.state {
+	case CircuitOpen:
+		return t, ErrUserSourceUnavailable
+	case CircuitHalfOpen:
+		if cb.probes >= cb.conf.HalfOpenRequests {
+			return t, ErrUserSourceUnavailable
+		}
+		cb.probes++
+	}
+	return t, nil
+}
+
+// record counts request result, not found, rate limited and rejected caller
+// token are not considered failures since the source is still responding.
+func (cb *CircuitBreaker) record(ctx context.Context, t ticket, err error) {
+	failed := err != nil &&
+		!errors.Is(err, ErrUserNotFound) &&
+		!errors.Is(err, ErrUserSourceRateLimited) &&
+		!errors.Is(err, ErrUserSourceUnauthorized)
+	// Caller gave up, it says nothing about the source.
+	canceled := failed && ctx.Err() == context.Canceled
+
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	// State changed since admitted, probe slots are already reset.
+	if t.generation != cb.generation {
+		return
+	}
+
+	now := time.Now()
+	switch cb.state {
+	case CircuitHalfOpen:
+		// Probe slot is given back even when canceled, otherwise
+		// the circuit stays half-open rejecting every request.
+		cb.probes--
+		if canceled {
+			return
+		}
+		if failed {
+			cb.open(now)
+			return
+		}
+		cb.close(now)
+	case CircuitClosed:
+		if canceled {
+			return
+		}
+		if now.Sub(cb.windowStart) > cb.conf.Window {
+			cb.resetCounts(now)
+		}
+		if failed {
+			cb.failures++
+		} else {
+			cb.successes++
+		}
+
+		total := cb.successes + cb.failures
+		if total >= cb.conf.MinRequests && float64(cb.failures)/float64(total) >= cb.conf.FailureRatio {
+			cb.open(now)
+		}
+	}
+}
+
+// checkCoolDown moves open circuit to half-open when cool down ends.
+func (cb *CircuitBreaker) checkCoolDown(now time.Time) {
+	if cb.state == CircuitOpen && now.Sub(cb.openedAt) >= cb.conf.CoolDown {
+		cb.setState(CircuitHalfOpen)
+	}
+}
+
+func (cb *CircuitBreaker) open(now time.Time) {
+	cb.setState(CircuitOpen)
+	cb.openedAt = now
+}
+
+func (cb *CircuitBreaker) close(now time.Time) {
+	cb.setState(CircuitClosed)
+	cb.resetCounts(now)
+}
+
+func (cb *CircuitBreaker) setState(s CircuitState) {
+	cb.state = s
+	cb.probes = 0
+	cb.generation++
+}
+
+func (cb *CircuitBreaker) resetCounts(now time.Time) {
+	cb.successes, cb.failures = 0, 0
+	cb.windowStart = now
+}
+
+// NewCircuitBreaker creates a new user source decorated with circuit breaker.
+func NewCircuitBreaker(source UserSource, conf CircuitBreakerConfig) *CircuitBreaker {
+	return &CircuitBreaker{source: source, conf: conf, windowStart: time.Now()}
+}
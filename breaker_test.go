@@ -0,0 +1,196 @@
+package ghsearch_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kudarap/ghsearch"
+)
+
+func TestCircuitBreaker_User(t *testing.T) {
+	conf := ghsearch.CircuitBreakerConfig{
+		FailureRatio:     0.5,
+		MinRequests:      4,
+		Window:           time.Minute,
+		CoolDown:         50 * time.Millisecond,
+		HalfOpenRequests: 1,
+	}
+	source := &switchableUserSource{err: ghsearch.ErrUserSourceTimeout}
+	cb := ghsearch.NewCircuitBreaker(source, conf)
+	ctx := context.Background()
+
+	// Not found should not count as failure.
+	source.err = ghsearch.ErrUserNotFound
+	for i := 0; i < 4; i++ {
+		cb.User(ctx, "kudarap")
+	}
+	assertCircuitState(t, cb, ghsearch.CircuitClosed)
+
+	// Trips open when failure ratio reached.
+	source.err = ghsearch.ErrUserSourceTimeout
+	for i := 0; i < 4; i++ {
+		cb.User(ctx, "kudarap")
+	}
+	assertCircuitState(t, cb, ghsearch.CircuitOpen)
+
+	// Fails fast without calling the source.
+	calls := source.calls
+	if _, err := cb.User(ctx, "kudarap"); !errors.Is(err, ghsearch.ErrUserSourceUnavailable) {
+		t.Errorf("err: %#v, want: %#v", err, ghsearch.ErrUserSourceUnavailable)
+	}
+	if source.calls != calls {
+		t.Errorf("calls: %d, want: %d", source.calls, calls)
+	}
+
+	// Failed probe opens the circuit again.
+	time.Sleep(conf.CoolDown)
+	assertCircuitState(t, cb, ghsearch.CircuitHalfOpen)
+	cb.User(ctx, "kudarap")
+	assertCircuitState(t, cb, ghsearch.CircuitOpen)
+
+	// Successful probe closes the circuit.
+	time.Sleep(conf.CoolDown)
+	source.err = nil
+	if _, err := cb.User(ctx, "kudarap"); err != nil {
+		t.Errorf("err: %#v, want: nil", err)
+	}
+	assertCircuitState(t, cb, ghsearch.CircuitClosed)
+
+	// Rejected caller token is not the source fault.
+	source.err = ghsearch.ErrUserSourceUnauthorized
+	cb.User(ctx, "kudarap")
+	if got := cb.Status().Failures; got != 0 {
+		t.Errorf("failures: %d, want: %d", got, 0)
+	}
+}
+
+func TestCircuitBreaker_Users(t *testing.T) {
+	conf := ghsearch.CircuitBreakerConfig{
+		FailureRatio:     0.5,
+		MinRequests:      2,
+		Window:           time.Minute,
+		CoolDown:         time.Minute,
+		HalfOpenRequests: 1,
+	}
+	ctx := context.Background()
+	usernames := []string{"kudarap", "dazz"}
+
+	// Batch source call counts as a single request.
+	source := &mockedBatchUserSource{batchErr: ghsearch.ErrUserSourceTimeout}
+	cb := ghsearch.NewCircuitBreaker(source, conf)
+	cb.Users(ctx, usernames)
+	if got := cb.Status().Failures; got != 1 {
+		t.Errorf("failures: %d, want: %d", got, 1)
+	}
+	cb.Users(ctx, usernames)
+	assertCircuitState(t, cb, ghsearch.CircuitOpen)
+	if _, err := cb.Users(ctx, usernames); !errors.Is(err, ghsearch.ErrUserSourceUnavailable) {
+		t.Errorf("err: %#v, want: %#v", err, ghsearch.ErrUserSourceUnavailable)
+	}
+	if source.calls != 2 {
+		t.Errorf("calls: %d, want: %d", source.calls, 2)
+	}
+
+	// Non-batch source counts each user.
+	cb = ghsearch.NewCircuitBreaker(&switchableUserSource{err: ghsearch.ErrUserSourceTimeout}, conf)
+	results, err := cb.Users(ctx, usernames)
+	if err != nil {
+		t.Fatalf("err: %#v, want: nil", err)
+	}
+	for _, r := range results {
+		if !errors.Is(r.Err, ghsearch.ErrUserSourceTimeout) {
+			t.Errorf("err: %#v, want: %#v", r.Err, ghsearch.ErrUserSourceTimeout)
+		}
+	}
+	assertCircuitState(t, cb, ghsearch.CircuitOpen)
+}
+
+func TestCircuitBreaker_User_CanceledProbe(t *testing.T) {
+	conf := ghsearch.CircuitBreakerConfig{
+		FailureRatio:     0.5,
+		MinRequests:      1,
+		Window:           time.Minute,
+		CoolDown:         10 * time.Millisecond,
+		HalfOpenRequests: 1,
+	}
+	source := &switchableUserSource{err: ghsearch.ErrUserSourceTimeout}
+	cb := ghsearch.NewCircuitBreaker(source, conf)
+	cb.User(context.Background(), "kudarap")
+	assertCircuitState(t, cb, ghsearch.CircuitOpen)
+
+	// Canceled probe gives back its slot.
+	time.Sleep(conf.CoolDown)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	cb.User(ctx, "kudarap")
+	assertCircuitState(t, cb, ghsearch.CircuitHalfOpen)
+
+	source.err = nil
+	if _, err := cb.User(context.Background(), "kudarap"); err != nil {
+		t.Errorf("err: %#v, want: nil", err)
+	}
+	assertCircuitState(t, cb, ghsearch.CircuitClosed)
+}
+
+func TestCircuitBreaker_User_StaleResult(t *testing.T) {
+	conf := ghsearch.CircuitBreakerConfig{
+		FailureRatio:     0.5,
+		MinRequests:      1,
+		Window:           time.Minute,
+		CoolDown:         10 * time.Millisecond,
+		HalfOpenRequests: 1,
+	}
+	release := make(chan struct{})
+	cb := ghsearch.NewCircuitBreaker(ghsearch.UserSourceFunc(func(ctx context.Context, username string) (*ghsearch.User, error) {
+		if username == "slow" {
+			<-release
+			return &ghsearch.User{Login: username}, nil
+		}
+		return nil, ghsearch.ErrUserSourceTimeout
+	}), conf)
+
+	// Admitted while closed and finishes after the circuit opened.
+	done := make(chan struct{})
+	go func() {
+		cb.User(context.Background(), "slow")
+		close(done)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	cb.User(context.Background(), "kudarap")
+	assertCircuitState(t, cb, ghsearch.CircuitOpen)
+	time.Sleep(conf.CoolDown)
+	assertCircuitState(t, cb, ghsearch.CircuitHalfOpen)
+
+	// Its late success neither closes the circuit nor frees a probe slot.
+	close(release)
+	<-done
+	assertCircuitState(t, cb, ghsearch.CircuitHalfOpen)
+	cb.User(context.Background(), "kudarap")
+	assertCircuitState(t, cb, ghsearch.CircuitOpen)
+}
+
+func assertCircuitState(t *testing.T, cb *ghsearch.CircuitBreaker, want ghsearch.CircuitState) {
+	t.Helper()
+	if got := cb.Status().State; got != want {
+		t.Errorf("state: %s, want: %s", got, want)
+	}
+}
+
+type switchableUserSource struct {
+	err   error
+	calls int
+	mu    sync.Mutex
+}
+
+func (s *switchableUserSource) User(ctx context.Context, username string) (*ghsearch.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls++
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &ghsearch.User{Login: username}, nil
+}
@@ -0,0 +1,96 @@
+package ghsearch
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCacheMiss indicates user is not cached.
+var ErrCacheMiss = errors.New("user not cached")
+
+// DefaultCacheFreshness sets how long cached users are considered fresh,
+// shorter max age makes the cache ask the source more often.
+const DefaultCacheFreshness = 2 * time.Minute
+
+// CachedUser represents cached user entry details for inspection.
+type CachedUser struct {
+	Username string
+	// User is nil when entry is a not found tombstone.
+	User     *User
+	NotFound bool
+	CachedAt time.Time
+	// TTL is how long until the entry is removed from the cache.
+	TTL time.Duration
+}
+
+// CachePolicy represents caller freshness requirement of cached users.
+type CachePolicy struct {
+	// NoCache requires a new user value from the source.
+	NoCache bool
+
+	// MaxAge accepts cached user up to this age, it may be longer or shorter
+	// than cache default freshness. Zero uses cache default.
+	MaxAge time.Duration
+}
+
+// Bypass checks if the policy asks the source sooner than cached users
+// are considered fresh, it costs the source the same as no cache.
+func (p CachePolicy) Bypass() bool {
+	return p.NoCache || (p.MaxAge > 0 && p.MaxAge < DefaultCacheFreshness)
+}
+
+type cachePolicyKey struct{}
+
+// WithCachePolicy returns a copy of ctx that carries cache policy for the sources.
+func WithCachePolicy(ctx context.Context, p CachePolicy) context.Context {
+	return context.WithValue(ctx, cachePolicyKey{}, p)
+}
+
+// CachePolicyFrom returns cache policy carried by ctx, zero value when none.
+func CachePolicyFrom(ctx context.Context) CachePolicy {
+	p, _ := ctx.Value(cachePolicyKey{}).(CachePolicy)
+	return p
+}
+
+type staleRecorderKey struct{}
+
+// staleRecorder collects usernames served with stale data by sources.
+type staleRecorder struct {
+	mu        sync.Mutex
+	usernames map[string]bool
+}
+
+func (r *staleRecorder) has(username string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.usernames[username]
+}
+
+func withStaleRecorder(ctx context.Context) (context.Context, *staleRecorder) {
+	r := &staleRecorder{usernames: map[string]bool{}}
+	return context.WithValue(ctx, staleRecorderKey{}, r), r
+}
+
+// MarkStale reports that user of username was served with stale data,
+// its a no-op when the caller does not track staleness.
+func MarkStale(ctx context.Context, username string) {
+	r, ok := ctx.Value(staleRecorderKey{}).(*staleRecorder)
+	if !ok {
+		return
+	}
+
+	r.mu.Lock()
+	r.usernames[username] = true
+	r.mu.Unlock()
+}
+
+// IsStale checks if user of username was marked stale by a source.
+func IsStale(ctx context.Context, username string) bool {
+	r, ok := ctx.Value(staleRecorderKey{}).(*staleRecorder)
+	if !ok {
+		return false
+	}
+	return r.has(username)
+}
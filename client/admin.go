@@ -0,0 +1,77 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/kudarap/ghsearch"
+)
+
+// CachedUser represents cached user entry.
+type CachedUser struct {
+	Username string `json:"username"`
+	// User is nil when entry is a not found tombstone.
+	User       *ghsearch.User `json:"user"`
+	NotFound   bool           `json:"not_found"`
+	CachedAt   time.Time      `json:"cached_at"`
+	AgeSeconds int            `json:"age_seconds"`
+	TTLSeconds int            `json:"ttl_seconds"`
+}
+
+// APIKeyUsage represents requests of an API key since the service instance started.
+type APIKeyUsage struct {
+	Key        string    `json:"key"`
+	Requests   int       `json:"requests"`
+	Rejected   int       `json:"rejected"`
+	LastUsedAt time.Time `json:"last_used_at"`
+}
+
+// CachedUser returns cached user entry.
+func (c *Client) CachedUser(ctx context.Context, username string) (*CachedUser, error) {
+	var u CachedUser
+	if err := c.do(ctx, http.MethodGet, cachedUserPath(username), c.adminHeader(), nil, &u); err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+// DeleteCachedUser evicts cached user.
+func (c *Client) DeleteCachedUser(ctx context.Context, username string) error {
+	return c.do(ctx, http.MethodDelete, cachedUserPath(username), c.adminHeader(), nil, nil)
+}
+
+// RefreshCachedUser force refresh cached user from GitHub.
+func (c *Client) RefreshCachedUser(ctx context.Context, username string) (*ghsearch.User, error) {
+	var u ghsearch.User
+	if err := c.do(ctx, http.MethodPost, cachedUserPath(username)+"/refresh", c.adminHeader(), nil, &u); err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+// DeleteCacheKeys evicts cached users with username matching pattern and returns how many were deleted.
+func (c *Client) DeleteCacheKeys(ctx context.Context, pattern string) (int, error) {
+	var resp struct {
+		Deleted int `json:"deleted"`
+	}
+	path := "/admin/cache/keys?" + url.Values{"pattern": {pattern}}.Encode()
+	if err := c.do(ctx, http.MethodDelete, path, c.adminHeader(), nil, &resp); err != nil {
+		return 0, err
+	}
+	return resp.Deleted, nil
+}
+
+// APIKeyUsage returns usage of each API key.
+func (c *Client) APIKeyUsage(ctx context.Context) ([]APIKeyUsage, error) {
+	var usage []APIKeyUsage
+	if err := c.do(ctx, http.MethodGet, "/admin/api-keys/usage", c.adminHeader(), nil, &usage); err != nil {
+		return nil, err
+	}
+	return usage, nil
+}
+
+func cachedUserPath(username string) string {
+	return "/admin/cache/users/" + url.PathEscape(username)
+}
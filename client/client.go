@@ -0,0 +1,198 @@
+// Package client provides typed Go client of gh-search http API
+// described by http/openapi.json.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kudarap/ghsearch"
+)
+
+// DefaultTimeout limits each request of the default http client,
+// SetHTTPClient with your own to change it.
+const DefaultTimeout = 10 * time.Second
+
+// Request header keys.
+const (
+	HeaderAPIKey      = "X-API-Key"
+	HeaderGithubToken = "X-GitHub-Token"
+	HeaderRequestID   = "X-Request-ID"
+)
+
+// Error codes of failed requests and lookups, see Error.Code and UserResult.Status.
+const (
+	CodeTooManyInput       = "too_many_input"
+	CodeUserNotFound       = "user_not_found"
+	CodeRateLimited        = "rate_limited"
+	CodeSourceTimeout      = "source_timeout"
+	CodeSourceFailed       = "source_failed"
+	CodeSourceUnavailable  = "source_unavailable"
+	CodeInternal           = "internal_error"
+	CodeCacheMiss          = "cache_miss"
+	CodeUnauthorized       = "unauthorized"
+	CodeInvalidInput       = "invalid_input"
+	CodeCacheBypassLimited = "cache_bypass_limited"
+	CodeClientRateLimited  = "client_rate_limited"
+	CodeForbidden          = "forbidden"
+	CodeSourceUnauthorized = "source_unauthorized"
+	CodeInvalidUsername    = "invalid_username"
+)
+
+// Error represents problem details of a failed request.
+type Error struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Detail    string `json:"detail"`
+	Code      string `json:"code"`
+	RequestID string `json:"request_id"`
+
+	// RetryAfter is how long until the request can be retried, only set on rate limited requests.
+	RetryAfter time.Duration `json:"-"`
+}
+
+func (e *Error) Error() string {
+	if e.Detail == "" {
+		return fmt.Sprintf("ghsearch: %d %s", e.Status, e.Code)
+	}
+	return fmt.Sprintf("ghsearch: %d %s: %s", e.Status, e.Code, e.Detail)
+}
+
+// Client represents gh-search http API client.
+type Client struct {
+	baseURL string
+
+	// custom httpClient for controlled request and timeouts
+	httpClient *http.Client
+
+	// apiKey is optional, required once the service has API keys.
+	apiKey string
+
+	// githubToken is optional, looks up users with caller own GitHub access token.
+	githubToken string
+
+	// adminToken is optional, required by admin methods.
+	adminToken string
+}
+
+// SetAPIKey sets API key sent on every request.
+func (c *Client) SetAPIKey(key string) {
+	c.apiKey = key
+}
+
+// SetGithubToken sets GitHub access token used by user lookups instead of the service tokens.
+func (c *Client) SetGithubToken(token string) {
+	c.githubToken = token
+}
+
+// SetAdminToken sets token of admin methods.
+func (c *Client) SetAdminToken(token string) {
+	c.adminToken = token
+}
+
+// SetHTTPClient replaces default http client.
+func (c *Client) SetHTTPClient(hc *http.Client) {
+	c.httpClient = hc
+}
+
+// NewClient creates new gh-search client of service on baseURL.
+func NewClient(baseURL string) *Client {
+	return &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{Timeout: DefaultTimeout},
+	}
+}
+
+// do sends request with JSON body when in is not nil and decodes response
+// body into out when its not nil, error responses returns *Error.
+func (c *Client) do(ctx context.Context, method, path string, header http.Header, in, out interface{}) error {
+	req, err := c.newRequest(ctx, method, path, header, in)
+	if err != nil {
+		return err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return decodeError(resp)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func (c *Client) newRequest(ctx context.Context, method, path string, header http.Header, in interface{}) (*http.Request, error) {
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.apiKey != "" {
+		req.Header.Set(HeaderAPIKey, c.apiKey)
+	}
+	// Forwards request id so service logs correlate with the caller request.
+	if id := ghsearch.RequestIDFrom(ctx); id != "" {
+		req.Header.Set(HeaderRequestID, id)
+	}
+	return req, nil
+}
+
+// adminHeader returns authorization header of admin methods.
+func (c *Client) adminHeader() http.Header {
+	return http.Header{"Authorization": {"Bearer " + c.adminToken}}
+}
+
+func decodeError(resp *http.Response) error {
+	e := &Error{Status: resp.StatusCode}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	// Errors outside the handlers like unknown routes are not problem details.
+	if json.Unmarshal(body, e) != nil || e.Code == "" {
+		e.Status = resp.StatusCode
+		e.Title = http.StatusText(resp.StatusCode)
+		e.Detail = strings.TrimSpace(string(body))
+	}
+	if e.RequestID == "" {
+		e.RequestID = resp.Header.Get(HeaderRequestID)
+	}
+	if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+		e.RetryAfter = time.Duration(secs) * time.Second
+	}
+	return e
+}
+
+// IsCode reports whether err is an *Error with code.
+func IsCode(err error, code string) bool {
+	var e *Error
+	return errors.As(err, &e) && e.Code == code
+}
@@ -0,0 +1,180 @@
+package client_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/kudarap/ghsearch"
+	"github.com/kudarap/ghsearch/client"
+)
+
+func TestClient_Users(t *testing.T) {
+	testcases := []struct {
+		name string
+		// deps
+		status int
+		header http.Header
+		body   string
+		// returns
+		want    []client.UserResult
+		wantErr error
+	}{
+		{
+			"found",
+			http.StatusOK,
+			nil,
+			`[{"username":"kudarap","status":"ok","user":{"login":"kudarap","followers":9}},{"username":"nope","status":"user_not_found","error":"user not found"}]`,
+			[]client.UserResult{
+				{Username: "kudarap", Status: client.StatusOK, User: &ghsearch.User{Login: "kudarap", Followers: 9}},
+				{Username: "nope", Status: client.CodeUserNotFound, Error: "user not found"},
+			},
+			nil,
+		},
+		{
+			"rate limited",
+			http.StatusTooManyRequests,
+			http.Header{"Retry-After": {"3"}},
+			`{"type":"about:blank","title":"Too Many Requests","status":429,"code":"client_rate_limited","request_id":"abc"}`,
+			nil,
+			&client.Error{
+				Type:       "about:blank",
+				Title:      "Too Many Requests",
+				Status:     http.StatusTooManyRequests,
+				Code:       client.CodeClientRateLimited,
+				RequestID:  "abc",
+				RetryAfter: 3 * time.Second,
+			},
+		},
+		{
+			"not problem details",
+			http.StatusBadGateway,
+			http.Header{client.HeaderRequestID: {"abc"}},
+			"bad gateway\n",
+			nil,
+			&client.Error{
+				Title:     "Bad Gateway",
+				Status:    http.StatusBadGateway,
+				Detail:    "bad gateway",
+				RequestID: "abc",
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			var gotReq *http.Request
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotReq = r
+				for k, v := range tc.header {
+					w.Header()[k] = v
+				}
+				w.WriteHeader(tc.status)
+				w.Write([]byte(tc.body))
+			}))
+			defer srv.Close()
+
+			c := client.NewClient(srv.URL + "/")
+			c.SetAPIKey("key")
+			c.SetGithubToken("token")
+			got, err := c.Users(context.Background(), []string{"kudarap", "nope"})
+			if !reflect.DeepEqual(err, tc.wantErr) {
+				t.Fatalf("err: %#v, want: %#v", err, tc.wantErr)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("\ngot: \n\t%#v \nwant: \n\t%#v", got, tc.want)
+			}
+
+			if got := gotReq.URL.String(); got != "/users?usernames=kudarap%2Cnope" {
+				t.Errorf("url: %s", got)
+			}
+			if gotReq.Header.Get(client.HeaderAPIKey) != "key" || gotReq.Header.Get(client.HeaderGithubToken) != "token" {
+				t.Errorf("headers: %#v", gotReq.Header)
+			}
+		})
+	}
+}
+
+func TestClient_SearchUsers(t *testing.T) {
+	var got map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		json.Unmarshal(b, &got)
+		w.Write([]byte(`[]`))
+	}))
+	defer srv.Close()
+
+	c := client.NewClient(srv.URL)
+	_, err := c.SearchUsers(context.Background(), client.SearchRequest{
+		Usernames: []string{"kudarap"},
+		Fields:    []string{"login"},
+		Cache:     &client.CachePolicy{MaxAge: time.Hour},
+		Timeout:   2 * time.Second,
+	})
+	if err != nil {
+		t.Fatal("search users:", err)
+	}
+
+	want := map[string]interface{}{
+		"usernames":  []interface{}{"kudarap"},
+		"fields":     []interface{}{"login"},
+		"cache":      map[string]interface{}{"max_age": 3600.0},
+		"timeout_ms": 2000.0,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("\ngot: \n\t%#v \nwant: \n\t%#v", got, want)
+	}
+}
+
+func TestClient_Ready(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(`{"status":"fail","checks":{"redis":{"status":"fail","latency_ms":1.5,"error":"down"}}}`))
+	}))
+	defer srv.Close()
+
+	got, err := client.NewClient(srv.URL).Ready(context.Background())
+	if err != nil {
+		t.Fatal("ready:", err)
+	}
+	want := &client.Health{
+		Status: client.HealthFail,
+		Checks: map[string]client.Check{"redis": {Status: client.HealthFail, LatencyMS: 1.5, Error: "down"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("\ngot: \n\t%#v \nwant: \n\t%#v", got, want)
+	}
+}
+
+func TestIsCode(t *testing.T) {
+	err := fmt.Errorf("lookup: %w", &client.Error{Code: client.CodeForbidden})
+	if !client.IsCode(err, client.CodeForbidden) {
+		t.Error("want forbidden code")
+	}
+	if client.IsCode(errors.New("forbidden"), client.CodeForbidden) {
+		t.Error("want no code of plain error")
+	}
+}
+
+func TestClient_CircuitStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"state":"half-open","successes":1,"failures":2}`))
+	}))
+	defer srv.Close()
+
+	got, err := client.NewClient(srv.URL).CircuitStatus(context.Background())
+	if err != nil {
+		t.Fatal("circuit status:", err)
+	}
+	want := &ghsearch.CircuitStatus{State: ghsearch.CircuitHalfOpen, Successes: 1, Failures: 2}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("\ngot: \n\t%#v \nwant: \n\t%#v", got, want)
+	}
+}
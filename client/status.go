@@ -0,0 +1,70 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/kudarap/ghsearch"
+)
+
+// Health statuses.
+const (
+	HealthOK           = "ok"
+	HealthFail         = "fail"
+	HealthShuttingDown = "shutting_down"
+)
+
+// Health represents service health and its dependency checks.
+type Health struct {
+	Status string           `json:"status"`
+	Checks map[string]Check `json:"checks"`
+}
+
+// Check represents a dependency readiness check result.
+type Check struct {
+	Status    string  `json:"status"`
+	LatencyMS float64 `json:"latency_ms"`
+	Error     string  `json:"error"`
+}
+
+// Health returns service liveness.
+func (c *Client) Health(ctx context.Context) (*Health, error) {
+	return c.health(ctx, "/healthz")
+}
+
+// Ready returns service readiness, not ready service returns
+// Health with failed status instead of an error.
+func (c *Client) Ready(ctx context.Context) (*Health, error) {
+	return c.health(ctx, "/readyz")
+}
+
+func (c *Client) health(ctx context.Context, path string) (*Health, error) {
+	req, err := c.newRequest(ctx, http.MethodGet, path, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusServiceUnavailable {
+		return nil, decodeError(resp)
+	}
+	var h Health
+	if err = json.NewDecoder(resp.Body).Decode(&h); err != nil {
+		return nil, err
+	}
+	return &h, nil
+}
+
+// CircuitStatus returns circuit breaker state of the user source.
+func (c *Client) CircuitStatus(ctx context.Context) (*ghsearch.CircuitStatus, error) {
+	var s ghsearch.CircuitStatus
+	if err := c.do(ctx, http.MethodGet, "/status/circuit-breaker", nil, nil, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
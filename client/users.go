@@ -0,0 +1,96 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/kudarap/ghsearch"
+)
+
+// StatusOK is the status of successful user lookup, failed lookups uses its error code.
+const StatusOK = "ok"
+
+// UserResult represents lookup result of a single requested username.
+type UserResult struct {
+	Username string `json:"username"`
+	Status   string `json:"status"`
+	// User is nil when lookup failed, only selected fields are set
+	// when SearchRequest.Fields is set.
+	User  *ghsearch.User `json:"user"`
+	Stale bool           `json:"stale"`
+	Error string         `json:"error"`
+}
+
+// SearchRequest represents users search options.
+type SearchRequest struct {
+	Usernames []string `json:"usernames"`
+	// Fields selects user fields to respond with, all when empty.
+	Fields []string `json:"fields,omitempty"`
+	// Cache overrides cache policy of the lookups, service default when nil.
+	Cache *CachePolicy `json:"cache,omitempty"`
+	// Timeout limits the lookups with millisecond precision, service default when zero.
+	Timeout time.Duration `json:"-"`
+}
+
+// CachePolicy represents freshness requirement of cached users.
+type CachePolicy struct {
+	// NoCache requires fresh users from GitHub.
+	NoCache bool `json:"no_cache,omitempty"`
+	// MaxAge accepts cached users up to this age with seconds precision.
+	MaxAge time.Duration `json:"-"`
+}
+
+type cachePolicyReq struct {
+	NoCache bool `json:"no_cache,omitempty"`
+	MaxAge  int  `json:"max_age,omitempty"`
+}
+
+type searchReq struct {
+	Usernames []string        `json:"usernames"`
+	Fields    []string        `json:"fields,omitempty"`
+	Cache     *cachePolicyReq `json:"cache,omitempty"`
+	TimeoutMS int64           `json:"timeout_ms,omitempty"`
+}
+
+// Users looks up usernames and returns each result in the same order as the input.
+func (c *Client) Users(ctx context.Context, usernames []string) ([]UserResult, error) {
+	q := url.Values{"usernames": {strings.Join(usernames, ",")}}
+	var results []UserResult
+	if err := c.do(ctx, http.MethodGet, "/users?"+q.Encode(), c.userHeader(), nil, &results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// SearchUsers looks up users with search options and returns each result
+// in the same order as the input.
+func (c *Client) SearchUsers(ctx context.Context, sr SearchRequest) ([]UserResult, error) {
+	req := searchReq{
+		Usernames: sr.Usernames,
+		Fields:    sr.Fields,
+		TimeoutMS: sr.Timeout.Milliseconds(),
+	}
+	if sr.Cache != nil {
+		req.Cache = &cachePolicyReq{
+			NoCache: sr.Cache.NoCache,
+			MaxAge:  int(sr.Cache.MaxAge.Seconds()),
+		}
+	}
+
+	var results []UserResult
+	if err := c.do(ctx, http.MethodPost, "/users/search", c.userHeader(), req, &results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+func (c *Client) userHeader() http.Header {
+	h := http.Header{}
+	if c.githubToken != "" {
+		h.Set(HeaderGithubToken, c.githubToken)
+	}
+	return h
+}
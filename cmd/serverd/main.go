@@ -1,15 +1,25 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/kudarap/ghsearch"
 	"github.com/kudarap/ghsearch/github"
 	"github.com/kudarap/ghsearch/http"
 	"github.com/kudarap/ghsearch/logging"
+	"github.com/kudarap/ghsearch/memory"
+	"github.com/kudarap/ghsearch/metrics"
 	"github.com/kudarap/ghsearch/redis"
+	"github.com/kudarap/ghsearch/tracing"
 )
 
 func main() {
@@ -39,34 +49,188 @@ type Application struct {
 }
 
 func (app *Application) setup() error {
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Config{
+		ServiceName: serviceName,
+		Exporter:    app.conf.TracingExporter,
+		Endpoint:    app.conf.TracingEndpoint,
+	})
+	if err != nil {
+		return fmt.Errorf("could not setup tracing: %s", err)
+	}
+
 	// Initialize dependencies
-	githubClient, err := github.NewClient(app.conf.GithubToken)
+	githubClient, err := github.NewTokenPool(app.conf.GithubTokens)
 	if err != nil {
 		return fmt.Errorf("could not setup github: %s", err)
 	}
-	redisClient, err := redis.NewClient(app.conf.RedisURL)
-	if err != nil {
-		return fmt.Errorf("could not setup redis: %s", err)
+	githubClient.SetRateLimitMaxWait(app.conf.GithubRateLimitMaxWait)
+	githubClient.SetRetryPolicy(app.conf.githubRetryPolicy())
+
+	// Redis is not needed when caching on memory only.
+	var redisClient *redis.Client
+	if app.conf.CacheMode != cacheModeMemory {
+		if redisClient, err = redis.NewClient(app.conf.RedisURL); err != nil {
+			return fmt.Errorf("could not setup redis: %s", err)
+		}
+		githubClient.SetValidatorCache(redisClient)
+		githubClient.SetRateLimitStore(redisClient)
 	}
 
-	userSourceCache := redis.NewUserSource(redisClient, githubClient)
-	userService := ghsearch.NewUserService(userSourceCache)
+	var userSource ghsearch.UserSource = githubClient
+	var graphQLClient *github.GraphQLPool
+	if app.conf.GithubGraphQL {
+		if graphQLClient, err = github.NewGraphQLPool(app.conf.GithubTokens); err != nil {
+			return fmt.Errorf("could not setup github graphql: %s", err)
+		}
+		graphQLClient.SetRateLimitMaxWait(app.conf.GithubRateLimitMaxWait)
+		graphQLClient.SetRetryPolicy(app.conf.githubRetryPolicy())
+		if redisClient != nil {
+			graphQLClient.SetRateLimitStore(redisClient)
+		}
+		userSource = graphQLClient
+	}
+
+	circuitBreaker := ghsearch.NewCircuitBreaker(userSource, ghsearch.DefaultCircuitBreakerConfig)
+	userSource = circuitBreaker
+	var userSourceCache *redis.UserSourceCache
+	if redisClient != nil {
+		userSourceCache = redis.NewUserSource(redisClient, userSource)
+		userSourceCache.SetNotFoundExpr(app.conf.NotFoundCacheExpr)
+		userSource = userSourceCache
+	}
+	var memoryCache *memory.UserSourceCache
+	if app.conf.CacheMode != cacheModeRedis {
+		memoryCache = memory.NewUserSource(memory.NewCache(app.conf.MemoryCacheSize), userSource)
+		memoryCache.SetExpr(app.conf.memoryCacheExpr(), app.conf.NotFoundCacheExpr)
+		userSource = memoryCache
+	}
+	userService := ghsearch.NewUserService(userSource)
 
 	restHandler := http.NewRestHandler(userService)
+	restHandler.SetCircuitBreaker(circuitBreaker)
+	restHandler.SetAdminToken(app.conf.AdminToken)
+	// Memory only cache has nothing shared to administer.
+	if userSourceCache != nil {
+		restHandler.SetCacheAdmin(userSourceCache)
+	}
+	apiKeys, err := app.apiKeyStore(redisClient)
+	if err != nil {
+		return fmt.Errorf("could not setup api keys: %s", err)
+	}
+	if apiKeys != nil {
+		restHandler.SetAPIKeys(apiKeys)
+	}
+	if err = restHandler.SetTrustedProxies(app.conf.TrustedProxies); err != nil {
+		return fmt.Errorf("could not setup trusted proxies: %s", err)
+	}
+	// Client buckets are kept per instance unless shared with redis.
+	var clientLimiter http.ClientRateLimiter = memory.NewRateLimiter(clientRateLimitCapacity)
+	if app.conf.ClientRateLimitRedis {
+		if redisClient == nil {
+			return fmt.Errorf("could not setup client rate limit: redis is not used on CACHE_MODE=%s", app.conf.CacheMode)
+		}
+		clientLimiter = redisClient
+	}
+	restHandler.SetCacheBypassLimit(clientLimiter, app.conf.CacheBypassLimit)
+	// API keys may have their own rate limit even when anonymous clients are not limited.
+	if app.conf.ClientRateLimit > 0 || apiKeys != nil {
+		restHandler.SetClientRateLimit(clientLimiter, http.ClientRateLimit{
+			Rate:  app.conf.ClientRateLimit,
+			Burst: app.conf.ClientRateLimitBurst,
+		})
+	}
+	if app.conf.MetricsEnabled {
+		m := metrics.New()
+		githubClient.SetObserver(m)
+		m.AddRateLimits(func() []metrics.RateLimit {
+			return rateLimitSamples("core", githubClient.RateLimits()...)
+		})
+		if graphQLClient != nil {
+			graphQLClient.SetObserver(m)
+			m.AddRateLimits(func() []metrics.RateLimit {
+				return rateLimitSamples("graphql", graphQLClient.RateLimits()...)
+			})
+		}
+		if userSourceCache != nil {
+			userSourceCache.SetObserver(m)
+		}
+		if memoryCache != nil {
+			memoryCache.SetObserver(m)
+		}
+		restHandler.SetMetrics(m)
+	}
+	// Rate limit headroom is reported but never fails readiness since
+	// exhausted tokens would take every instance out at once.
+	restHandler.AddReadinessReport("github", http.CacheReadinessReport(func(ctx context.Context) (interface{}, error) {
+		return githubClient.RateLimitHeadroom(ctx)
+	}, readinessCacheExpr))
+	if graphQLClient != nil {
+		restHandler.AddReadinessReport("github_graphql", http.CacheReadinessReport(func(ctx context.Context) (interface{}, error) {
+			return graphQLClient.RateLimitHeadroom(ctx)
+		}, readinessCacheExpr))
+	}
+	if redisClient != nil {
+		restHandler.AddReadinessCheck("redis", redisClient.Ping)
+	}
 	app.server = http.NewServer(app.conf.Addr, restHandler, app.log)
+	app.server.SetDrainDelay(app.conf.ShutdownDrainDelay)
 	app.closeFn = func() error {
-		return redisClient.Close()
+		// Each is closed regardless of the other failing.
+		ctx, cancel := context.WithTimeout(context.Background(), tracingShutdownTimeout)
+		defer cancel()
+		var errs []string
+		if err := shutdownTracing(ctx); err != nil {
+			errs = append(errs, fmt.Sprintf("could not shutdown tracing: %s", err))
+		}
+		if redisClient != nil {
+			if err := redisClient.Close(); err != nil {
+				errs = append(errs, fmt.Sprintf("could not close redis: %s", err))
+			}
+		}
+		if len(errs) > 0 {
+			return errors.New(strings.Join(errs, "; "))
+		}
+		return nil
 	}
 	return nil
 }
 
+// apiKeyStore returns API keys from config file or redis, nil when both are not set.
+func (app *Application) apiKeyStore(redisClient *redis.Client) (ghsearch.APIKeyStore, error) {
+	switch {
+	case app.conf.APIKeysFile != "" && app.conf.APIKeysRedis:
+		return nil, fmt.Errorf("API_KEYS_FILE and API_KEYS_REDIS are exclusive")
+	case app.conf.APIKeysFile != "":
+		b, err := os.ReadFile(app.conf.APIKeysFile)
+		if err != nil {
+			return nil, err
+		}
+		keys := map[string]ghsearch.APIKey{}
+		if err = json.Unmarshal(b, &keys); err != nil {
+			return nil, err
+		}
+		return memory.NewAPIKeys(keys), nil
+	case app.conf.APIKeysRedis:
+		if redisClient == nil {
+			return nil, fmt.Errorf("redis is not used on CACHE_MODE=%s", app.conf.CacheMode)
+		}
+		// Avoids a redis round trip on every request.
+		return memory.NewAPIKeyStore(
+			memory.NewCache(apiKeyCacheCapacity),
+			memory.NewCache(apiKeyNotFoundCacheCapacity),
+			redis.NewAPIKeys(redisClient),
+		), nil
+	}
+	return nil, nil
+}
+
 func (app *Application) run() error {
 	return app.server.Run()
 }
 
 func (app *Application) close() {
 	if err := app.closeFn(); err != nil {
-		app.log.Errorln("error when closing app: %s", err)
+		app.log.Errorf("error when closing app: %s", err)
 	}
 }
 
@@ -74,10 +238,97 @@ func newApp() *Application {
 	return &Application{log: logging.New()}
 }
 
+const (
+	// serviceName identifies this service on traces.
+	serviceName = "gh-search"
+
+	// tracingShutdownTimeout limits flushing remaining spans on close.
+	tracingShutdownTimeout = 5 * time.Second
+
+	// readinessCacheExpr sets how long GitHub readiness result is reused between probes.
+	readinessCacheExpr = 30 * time.Second
+)
+
+// clientRateLimitCapacity bounds number of clients tracked on memory.
+const clientRateLimitCapacity = 10000
+
+// apiKeyCacheCapacity bounds number of redis API keys cached on memory.
+const apiKeyCacheCapacity = 1000
+
+// apiKeyNotFoundCacheCapacity bounds number of unknown API keys cached on
+// memory, smaller since these are mostly guesses.
+const apiKeyNotFoundCacheCapacity = 100
+
+// Cache modes.
+const (
+	// cacheModeRedis caches users on redis shared between instances.
+	cacheModeRedis = "redis"
+	// cacheModeMemory caches users on memory, good for single instance or demo.
+	cacheModeMemory = "memory"
+	// cacheModeTiered caches users on memory in front of redis.
+	cacheModeTiered = "tiered"
+)
+
+// memoryL1CacheExpr keeps users on memory shortly in front of redis
+// so it does not hide redis cache revalidation for long.
+const memoryL1CacheExpr = 30 * time.Second
+
 type Config struct {
-	Addr        string
-	RedisURL    string
-	GithubToken string
+	Addr     string
+	RedisURL string
+
+	// CacheMode selects where users are cached, redis by default.
+	CacheMode       string
+	MemoryCacheSize int
+
+	// GithubTokens are rotated to increase available rate limits.
+	GithubTokens []string
+
+	// GithubGraphQL uses GraphQL API to lookup users in batches, rotating
+	// between GithubTokens the same way as REST API.
+	GithubGraphQL bool
+
+	// GithubRateLimitMaxWait sets how long requests can wait for rate limit
+	// reset when exhausted, zero fails immediately.
+	GithubRateLimitMaxWait time.Duration
+
+	// GithubRetryMaxAttempts overrides default retry attempts on transient
+	// failures, one disables retry.
+	GithubRetryMaxAttempts int
+
+	// NotFoundCacheExpr sets how long not found users are cached, zero disables it.
+	NotFoundCacheExpr time.Duration
+
+	// CacheBypassLimit sets how many no-cache requests each client is allowed per minute.
+	CacheBypassLimit int
+
+	// MetricsEnabled exposes Prometheus metrics on /metrics.
+	MetricsEnabled bool
+
+	// ShutdownDrainDelay keeps serving while not ready before shutting down.
+	ShutdownDrainDelay time.Duration
+
+	// TracingExporter sends spans to stdout or otlp, disabled when empty.
+	TracingExporter string
+	// TracingEndpoint sets OTLP collector host:port.
+	TracingEndpoint string
+
+	// AdminToken enables cache admin endpoints, disabled when empty.
+	AdminToken string
+
+	// ClientRateLimit sets requests per second allowed per client, zero disables it.
+	ClientRateLimit float64
+	// ClientRateLimitBurst sets requests a client can send at once, defaults to a second worth.
+	ClientRateLimitBurst int
+	// ClientRateLimitRedis shares client limits between instances through redis.
+	ClientRateLimitRedis bool
+	// TrustedProxies are CIDRs or IPs allowed to forward client address.
+	TrustedProxies []string
+
+	// APIKeysFile is a JSON file of API keys mapped by the key itself.
+	APIKeysFile string
+	// APIKeysRedis reads API keys from redis stored by key hash.
+	APIKeysRedis bool
 }
 
 func (c *Config) loadFromEnv() error {
@@ -86,6 +337,133 @@ func (c *Config) loadFromEnv() error {
 
 	c.Addr = os.Getenv("ADDR")
 	c.RedisURL = os.Getenv("REDIS_URL")
-	c.GithubToken = os.Getenv("GITHUB_TOKEN")
+	c.AdminToken = os.Getenv("ADMIN_TOKEN")
+	c.CacheMode = strings.ToLower(os.Getenv("CACHE_MODE"))
+	switch c.CacheMode {
+	case "":
+		c.CacheMode = cacheModeRedis
+	case cacheModeRedis, cacheModeMemory, cacheModeTiered:
+	default:
+		return fmt.Errorf("unknown CACHE_MODE: %s", c.CacheMode)
+	}
+	if v := os.Getenv("MEMORY_CACHE_SIZE"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("could not parse MEMORY_CACHE_SIZE: %s", err)
+		}
+		c.MemoryCacheSize = n
+	}
+	c.CacheBypassLimit = http.DefaultCacheBypassLimit
+	if v := os.Getenv("CACHE_BYPASS_LIMIT"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("could not parse CACHE_BYPASS_LIMIT: %s", err)
+		}
+		c.CacheBypassLimit = n
+	}
+	if v := os.Getenv("CLIENT_RATE_LIMIT"); v != "" {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return fmt.Errorf("could not parse CLIENT_RATE_LIMIT: %s", err)
+		}
+		c.ClientRateLimit = f
+	}
+	c.ClientRateLimitBurst = int(math.Max(1, math.Ceil(c.ClientRateLimit)))
+	if v := os.Getenv("CLIENT_RATE_LIMIT_BURST"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("could not parse CLIENT_RATE_LIMIT_BURST: %s", err)
+		}
+		c.ClientRateLimitBurst = n
+	}
+	c.ClientRateLimitRedis, _ = strconv.ParseBool(os.Getenv("CLIENT_RATE_LIMIT_REDIS"))
+	c.TrustedProxies = splitTokens(os.Getenv("TRUSTED_PROXIES"))
+	c.APIKeysFile = os.Getenv("API_KEYS_FILE")
+	c.APIKeysRedis, _ = strconv.ParseBool(os.Getenv("API_KEYS_REDIS"))
+	c.GithubTokens = splitTokens(os.Getenv("GITHUB_TOKEN"))
+	if f := os.Getenv("GITHUB_TOKEN_FILE"); f != "" {
+		b, err := os.ReadFile(f)
+		if err != nil {
+			return fmt.Errorf("could not read token file: %s", err)
+		}
+		c.GithubTokens = append(c.GithubTokens, splitTokens(string(b))...)
+	}
+	c.GithubGraphQL, _ = strconv.ParseBool(os.Getenv("GITHUB_GRAPHQL"))
+	c.MetricsEnabled, _ = strconv.ParseBool(os.Getenv("METRICS_ENABLED"))
+	if v := os.Getenv("SHUTDOWN_DRAIN_DELAY"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("could not parse SHUTDOWN_DRAIN_DELAY: %s", err)
+		}
+		c.ShutdownDrainDelay = d
+	}
+	c.TracingExporter = strings.ToLower(os.Getenv("TRACING_EXPORTER"))
+	c.TracingEndpoint = os.Getenv("TRACING_ENDPOINT")
+	if v := os.Getenv("GITHUB_RATE_LIMIT_MAX_WAIT"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("could not parse GITHUB_RATE_LIMIT_MAX_WAIT: %s", err)
+		}
+		c.GithubRateLimitMaxWait = d
+	}
+	c.NotFoundCacheExpr = redis.DefaultNotFoundCacheExpr
+	if v := os.Getenv("NOT_FOUND_CACHE_EXPR"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("could not parse NOT_FOUND_CACHE_EXPR: %s", err)
+		}
+		c.NotFoundCacheExpr = d
+	}
+	if v := os.Getenv("GITHUB_RETRY_MAX_ATTEMPTS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("could not parse GITHUB_RETRY_MAX_ATTEMPTS: %s", err)
+		}
+		c.GithubRetryMaxAttempts = n
+	}
 	return nil
 }
+
+func (c *Config) memoryCacheExpr() time.Duration {
+	if c.CacheMode == cacheModeTiered {
+		return memoryL1CacheExpr
+	}
+	return memory.DefaultUserCacheExpr
+}
+
+func (c *Config) githubRetryPolicy() github.RetryPolicy {
+	p := github.DefaultRetryPolicy
+	if c.GithubRetryMaxAttempts > 0 {
+		p.MaxAttempts = c.GithubRetryMaxAttempts
+	}
+	return p
+}
+
+// rateLimitSamples labels rate limits by token position,
+// tokens themselves are secrets and never exposed.
+func rateLimitSamples(resource string, rr ...github.RateLimit) []metrics.RateLimit {
+	samples := make([]metrics.RateLimit, len(rr))
+	for i, rl := range rr {
+		samples[i] = metrics.RateLimit{
+			Resource:  resource,
+			Token:     strconv.Itoa(i),
+			Limit:     rl.Limit,
+			Remaining: rl.Remaining,
+			ResetsAt:  rl.ResetsAt,
+		}
+	}
+	return samples
+}
+
+// splitTokens parses comma or newline separated values.
+func splitTokens(s string) []string {
+	var tokens []string
+	for _, t := range strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == '\n' || r == '\r'
+	}) {
+		if t = strings.TrimSpace(t); t != "" {
+			tokens = append(tokens, t)
+		}
+	}
+	return tokens
+}
@@ -1,6 +1,9 @@
 package ghsearch
 
-import "errors"
+import (
+	"errors"
+	"time"
+)
 
 var (
 	// ErrUserSourceFailed indicates user source process failed.
@@ -8,6 +11,18 @@ var (
 
 	// ErrUserSourceTimeout indicates user source process has timed out.
 	ErrUserSourceTimeout = errors.New("user source timed out")
+
+	// ErrUserSourceRateLimited indicates user source refused the request
+	// due to rate limits.
+	ErrUserSourceRateLimited = errors.New("user source rate limited")
+
+	// ErrUserSourceUnavailable indicates user source was not called
+	// since it keeps failing and given time to recover.
+	ErrUserSourceUnavailable = errors.New("user source unavailable")
+
+	// ErrUserSourceUnauthorized indicates user source rejected the access
+	// token supplied by the caller.
+	ErrUserSourceUnauthorized = errors.New("user source rejected access token")
 )
 
 // SourceError represents an error from a source.
@@ -19,6 +34,10 @@ func (e SourceError) Error() string {
 	return e.Err.Error()
 }
 
+func (e SourceError) Unwrap() error {
+	return e.Err
+}
+
 // NewSourceError returns an error a new SourceError contains error details.
 func NewSourceError(e error) error {
 	if e == nil {
@@ -26,3 +45,33 @@ func NewSourceError(e error) error {
 	}
 	return &SourceError{e}
 }
+
+// RateLimitError represents a source error caused by reaching its rate limit.
+type RateLimitError struct {
+	Err error
+
+	// ResetsAt indicates when the source will accept requests again,
+	// zero value means its unknown.
+	ResetsAt time.Time
+}
+
+func (e RateLimitError) Error() string {
+	return e.Err.Error()
+}
+
+func (e RateLimitError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports RateLimitError as ErrUserSourceRateLimited regardless of its cause.
+func (e RateLimitError) Is(target error) bool {
+	return target == ErrUserSourceRateLimited
+}
+
+// NewRateLimitError returns a new RateLimitError contains error and reset time.
+func NewRateLimitError(e error, resetsAt time.Time) error {
+	if e == nil {
+		return nil
+	}
+	return &RateLimitError{e, resetsAt}
+}
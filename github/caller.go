@@ -0,0 +1,109 @@
+package github
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/kudarap/ghsearch"
+)
+
+// maxCallerClients bounds clients kept for caller supplied access tokens.
+const maxCallerClients = 1000
+
+// ErrBadCredentials indicates GitHub rejected caller supplied access token.
+var ErrBadCredentials = fmt.Errorf("github: bad credentials: %w", ghsearch.ErrUserSourceUnauthorized)
+
+// ErrTokenForbidden indicates GitHub refused caller supplied access token
+// like when its missing a scope or SSO authorization.
+var ErrTokenForbidden = fmt.Errorf("github: token forbidden: %w", ghsearch.ErrUserSourceUnauthorized)
+
+// callerClients keeps a client per caller supplied access token so each token
+// tracks its own rate limit. Clients are keyed by token hash so the token is
+// never kept as a key, least recently used is dropped when its full.
+type callerClients struct {
+	mu      sync.Mutex
+	clients map[[sha256.Size]byte]*callerClient
+}
+
+type callerClient struct {
+	client interface{}
+	usedAt time.Time
+}
+
+// get returns client of the token, newClient creates one when there is none.
+func (cc *callerClients) get(token string, newClient func(token string) interface{}) interface{} {
+	key := sha256.Sum256([]byte(token))
+	now := time.Now()
+
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	if c, ok := cc.clients[key]; ok {
+		c.usedAt = now
+		return c.client
+	}
+
+	if cc.clients == nil {
+		cc.clients = map[[sha256.Size]byte]*callerClient{}
+	}
+	if len(cc.clients) >= maxCallerClients {
+		cc.evictOldest()
+	}
+	c := &callerClient{newClient(token), now}
+	cc.clients[key] = c
+	return c.client
+}
+
+func (cc *callerClients) evictOldest() {
+	var oldestKey [sha256.Size]byte
+	var oldest *callerClient
+	for k, c := range cc.clients {
+		if oldest == nil || c.usedAt.Before(oldest.usedAt) {
+			oldestKey, oldest = k, c
+		}
+	}
+	delete(cc.clients, oldestKey)
+}
+
+// withToken creates a client of caller access token with the same settings,
+// its rate limit is tracked separately and unknown until the first response.
+func (c *Client) withToken(accessToken string) *Client {
+	return &Client{
+		baseURL:        c.baseURL,
+		accessToken:    accessToken,
+		caller:         true,
+		httpClient:     c.httpClient,
+		validatorCache: c.validatorCache,
+		rateLimiter:    c.rateLimiter.derive(rateLimitStoreKey("core", accessToken)),
+		retryPolicy:    c.retryPolicy,
+		observer:       c.observer,
+	}
+}
+
+// withToken creates a GraphQL client of caller access token with the same settings.
+func (c *GraphQLClient) withToken(accessToken string) *GraphQLClient {
+	return &GraphQLClient{
+		client:      c.client.withToken(accessToken),
+		rateLimiter: c.rateLimiter.derive(rateLimitStoreKey("graphql", accessToken)),
+	}
+}
+
+// unauthorizedError returns caller error when caller access token was
+// rejected or refused, service token rejection is a source failure instead.
+// Rate limited responses should be checked before this.
+func (c *Client) unauthorizedError(resp *http.Response) error {
+	if !c.caller {
+		return nil
+	}
+	switch resp.StatusCode {
+	case http.StatusUnauthorized:
+		resp.Body.Close()
+		return ErrBadCredentials
+	case http.StatusForbidden:
+		resp.Body.Close()
+		return ErrTokenForbidden
+	}
+	return nil
+}
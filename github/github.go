@@ -1,27 +1,32 @@
 package github
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"strings"
 	"time"
 
+	"github.com/kudarap/ghsearch"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 	"golang.org/x/sync/singleflight"
 )
 
 var (
 	ErrReqFailed    = errors.New("github: request failed")
-	ErrRateLimitHit = errors.New("github: rate limit reached")
+	ErrRateLimitHit = fmt.Errorf("github: rate limit reached: %w", ghsearch.ErrUserSourceRateLimited)
 )
 
 const (
 	APIBaseURL           = "https://api.github.com"
 	APIUserEndpoint      = "/users"
 	APIRateLimitEndpoint = "/rate_limit"
+	APIGraphQLEndpoint   = "/graphql"
 
 	DefaultTimeout = 2 * time.Second
 )
@@ -34,33 +39,144 @@ const (
 	HeaderRateLimitUsed      = "x-ratelimit-used"
 )
 
+// Conditional request header keys.
+const (
+	HeaderETag            = "etag"
+	HeaderLastModified    = "last-modified"
+	HeaderIfNoneMatch     = "if-none-match"
+	HeaderIfModifiedSince = "if-modified-since"
+	HeaderRequestID       = "x-request-id"
+)
+
+// Cache represents a key-value store for persisting response validators
+// used on conditional requests.
+type Cache interface {
+	Get(ctx context.Context, key string, out interface{}) (ok bool, err error)
+	Set(ctx context.Context, key string, val interface{}, expr time.Duration) error
+}
+
 // Client represents GitHub client service.
 type Client struct {
 	baseURL     string
 	accessToken string
 
+	// caller indicates access token is supplied by the caller, see TokenPool.User.
+	caller bool
+
 	// custom httpClient for controlled request and timeouts
 	httpClient *http.Client
 
 	// requestGroup to prevent duplicate in-flight requests
 	requestGroup singleflight.Group
 
-	// current rate limits
-	RateLimit RateLimit
+	// validatorCache stores ETag and Last-Modified of responses for
+	// conditional requests, disabled when nil.
+	validatorCache Cache
+
+	// rateLimiter keeps track of current rate limits
+	rateLimiter *RateLimiter
+
+	// retryPolicy for transient request failures, zero value disables retry.
+	retryPolicy RetryPolicy
+
+	// observer receives client events for instrumentation, disabled when nil.
+	observer Observer
+}
+
+// Observer receives GitHub client events for instrumentation.
+type Observer interface {
+	// GithubRequest reports an API call by api name, either rest or graphql,
+	// and response status code, zero status when there was no response.
+	GithubRequest(api string, status int)
+
+	// RequestDeduped reports a request that shared the result of
+	// an in-flight duplicate request.
+	RequestDeduped(group string)
+}
+
+// SetObserver enables client instrumentation.
+func (c *Client) SetObserver(o Observer) {
+	c.observer = o
+}
+
+// SetValidatorCache enables conditional requests by storing response validators on cache.
+func (c *Client) SetValidatorCache(vc Cache) {
+	c.validatorCache = vc
 }
 
 // getRequest sends GET request and uses access token when available to increase rate limits.
-func (c *Client) getRequest(ctx context.Context, path string) (*http.Response, error) {
+// Retries are accounted on rl, nil when the endpoint does not count against rate limit.
+func (c *Client) getRequest(ctx context.Context, path string, rl *RateLimiter) (*http.Response, error) {
+	return c.conditionalGetRequest(ctx, path, rl, nil)
+}
+
+// conditionalGetRequest sends GET request with validators from previous response.
+func (c *Client) conditionalGetRequest(ctx context.Context, path string, rl *RateLimiter, v *validators) (*http.Response, error) {
+	return c.doRequest(ctx, rl, func() (*http.Request, error) {
+		req, err := c.newRequest(ctx, http.MethodGet, path, nil)
+		if err != nil {
+			return nil, err
+		}
+		if v != nil {
+			if v.ETag != "" {
+				req.Header.Set(HeaderIfNoneMatch, v.ETag)
+			}
+			if v.LastModified != "" {
+				req.Header.Set(HeaderIfModifiedSince, v.LastModified)
+			}
+		}
+		return req, nil
+	})
+}
+
+// postRequest sends POST request with JSON body, retries are accounted on rl.
+func (c *Client) postRequest(ctx context.Context, path string, rl *RateLimiter, in interface{}) (*http.Response, error) {
+	b, err := json.Marshal(in)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.doRequest(ctx, rl, func() (*http.Request, error) {
+		req, err := c.newRequest(ctx, http.MethodPost, path, bytes.NewReader(b))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		return req, nil
+	})
+}
+
+func (c *Client) newRequest(ctx context.Context, method, path string, body io.Reader) (*http.Request, error) {
 	url := fmt.Sprintf("%s%s", c.baseURL, path)
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
 	if err != nil {
 		return nil, err
 	}
 	if c.accessToken != "" {
 		req.Header.Set("Authorization", fmt.Sprintf("token %s", c.accessToken))
 	}
+	// Forwards request id so upstream calls correlate with the client request.
+	if id := ghsearch.RequestIDFrom(ctx); id != "" {
+		req.Header.Set(HeaderRequestID, id)
+	}
+	return req, nil
+}
+
+// validators represents response validators for conditional requests.
+type validators struct {
+	ETag         string `json:"etag"`
+	LastModified string `json:"last_modified"`
+}
+
+func validatorsFrom(h http.Header) validators {
+	return validators{
+		ETag:         h.Get(HeaderETag),
+		LastModified: h.Get(HeaderLastModified),
+	}
+}
 
-	return c.httpClient.Do(req)
+func (v validators) empty() bool {
+	return v.ETag == "" && v.LastModified == ""
 }
 
 // NewClient initializes GitHub client and setup rate limits.
@@ -70,6 +186,7 @@ func NewClient(accessToken string) (*Client, error) {
 	}
 
 	c := NewCustomClient(APIBaseURL, accessToken, DefaultTimeout)
+	c.SetRetryPolicy(DefaultRetryPolicy)
 	if err := c.acquireRateLimit(); err != nil {
 		return nil, err
 	}
@@ -85,7 +202,9 @@ func NewCustomClient(url, accessToken string, timeout time.Duration) *Client {
 	var c Client
 	c.baseURL = url
 	c.accessToken = accessToken
-	c.httpClient = &http.Client{Timeout: timeout}
+	// Each request attempt is traced as a client span with trace context propagated.
+	c.httpClient = &http.Client{Timeout: timeout, Transport: otelhttp.NewTransport(http.DefaultTransport)}
+	c.rateLimiter = NewRateLimiter(RateLimit{})
 	return &c
 }
 
@@ -102,5 +221,5 @@ func decodeBody(r *http.Response, out interface{}) error {
 }
 
 func responseHasError(r *http.Response) bool {
-	return r.StatusCode >= 400 && r.StatusCode <= 500
+	return r.StatusCode >= 400
 }
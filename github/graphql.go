@@ -0,0 +1,353 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kudarap/ghsearch"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// MaxGraphQLBatchSize represents maximum number of users resolved on a single query,
+// larger input will be split into multiple queries.
+const MaxGraphQLBatchSize = 50
+
+// GraphQL error types.
+const (
+	graphQLErrNotFound    = "NOT_FOUND"
+	graphQLErrRateLimited = "RATE_LIMITED"
+)
+
+// GraphQLClient represents GitHub GraphQL API client that resolves
+// multiple users on a single request using query aliases.
+//
+// Unlike REST API, user query only resolves user accounts and
+// organization logins will be reported as not found.
+type GraphQLClient struct {
+	client *Client
+
+	// rateLimiter keeps track of GraphQL rate limits in points,
+	// its separate from REST API.
+	rateLimiter *RateLimiter
+
+	// callers keeps clients of caller supplied access tokens.
+	callers callerClients
+}
+
+// RateLimit returns a snapshot of current GraphQL rate limit.
+func (c *GraphQLClient) RateLimit() RateLimit {
+	return c.rateLimiter.RateLimit()
+}
+
+// SetRateLimit replaces current GraphQL rate limit.
+func (c *GraphQLClient) SetRateLimit(rl RateLimit) {
+	c.rateLimiter.Set(rl)
+}
+
+// SetRateLimitMaxWait sets maximum duration to wait for rate limit reset
+// before sending a request, zero value fails immediately.
+func (c *GraphQLClient) SetRateLimitMaxWait(d time.Duration) {
+	c.rateLimiter.SetMaxWait(d)
+}
+
+// SetRetryPolicy sets how transient request failures are retried.
+func (c *GraphQLClient) SetRetryPolicy(p RetryPolicy) {
+	c.client.SetRetryPolicy(p)
+}
+
+// requestRateLimit requests current GraphQL rate limit and updates tracked rate limit.
+func (c *GraphQLClient) requestRateLimit(ctx context.Context) (*RateLimit, error) {
+	r, err := c.client.requestRateLimitResources(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rl := r.GraphQLRateLimit()
+	c.rateLimiter.Update(ctx, *rl)
+	return rl, nil
+}
+
+// SetObserver enables client instrumentation.
+func (c *GraphQLClient) SetObserver(o Observer) {
+	c.client.SetObserver(o)
+}
+
+// SetRateLimitStore shares GraphQL rate limit budget of the access token with other instances.
+func (c *GraphQLClient) SetRateLimitStore(store RateLimitStore) {
+	c.rateLimiter.SetStore(store, rateLimitStoreKey("graphql", c.client.accessToken))
+}
+
+// User returns Github user details by username.
+func (c *GraphQLClient) User(ctx context.Context, username string) (*ghsearch.User, error) {
+	rr, err := c.Users(ctx, []string{username})
+	if err != nil {
+		return nil, err
+	}
+	return rr[0].User, rr[0].Err
+}
+
+// Users returns Github user details of usernames using batched queries.
+// Caller access token on ctx is used instead of the client token.
+func (c *GraphQLClient) Users(ctx context.Context, usernames []string) ([]ghsearch.UserResult, error) {
+	if token := ghsearch.SourceTokenFrom(ctx); token != "" {
+		return c.callerClient(token).users(ctx, usernames)
+	}
+	return c.users(ctx, usernames)
+}
+
+func (c *GraphQLClient) callerClient(token string) *GraphQLClient {
+	return c.callers.get(token, func(token string) interface{} {
+		return c.withToken(token)
+	}).(*GraphQLClient)
+}
+
+func (c *GraphQLClient) users(ctx context.Context, usernames []string) ([]ghsearch.UserResult, error) {
+	results := make([]ghsearch.UserResult, 0, len(usernames))
+	for start := 0; start < len(usernames); start += MaxGraphQLBatchSize {
+		end := start + MaxGraphQLBatchSize
+		if end > len(usernames) {
+			end = len(usernames)
+		}
+
+		rr, err := c.fetchUsers(ctx, usernames[start:end])
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, rr...)
+	}
+	return results, nil
+}
+
+func (c *GraphQLClient) fetchUsers(ctx context.Context, usernames []string) (results []ghsearch.UserResult, err error) {
+	ctx, span := tracer().Start(ctx, "github.GraphQLClient.Users", trace.WithAttributes(
+		attribute.Int("github.usernames.count", len(usernames)),
+	))
+	defer func() { endSpan(span, err) }()
+
+	if err := c.rateLimiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+
+	resp, err := c.client.postRequest(ctx, APIGraphQLEndpoint, c.rateLimiter, newUsersQuery(usernames))
+	if err != nil {
+		if os.IsTimeout(err) {
+			return nil, ghsearch.ErrUserSourceTimeout
+		}
+		return nil, err
+	}
+	if responseRateLimited(resp) {
+		c.rateLimiter.updateFrom(ctx, resp.Header)
+		traceResponse(span, resp.StatusCode, c.rateLimiter.RateLimit())
+		return nil, ghsearch.NewRateLimitError(ErrRateLimitHit, rateLimitFrom(resp.Header).ResetsAt)
+	}
+	if err = c.client.unauthorizedError(resp); err != nil {
+		traceResponse(span, resp.StatusCode, c.rateLimiter.RateLimit())
+		return nil, err
+	}
+	if responseHasError(resp) {
+		traceResponse(span, resp.StatusCode, c.rateLimiter.RateLimit())
+		return nil, ghsearch.ErrUserSourceFailed
+	}
+
+	var r graphQLUsersResponse
+	if err = decodeBody(resp, &r); err != nil {
+		return nil, err
+	}
+	if rl := r.Data.RateLimit; rl != nil {
+		c.rateLimiter.Update(ctx, rl.rateLimit())
+	}
+	traceResponse(span, resp.StatusCode, c.rateLimiter.RateLimit())
+	if err = r.batchError(c.rateLimiter.RateLimit().ResetsAt); err != nil {
+		return nil, err
+	}
+
+	aliasErrs := r.aliasErrors()
+	results = make([]ghsearch.UserResult, len(usernames))
+	for i, uname := range usernames {
+		alias := userAlias(i)
+		results[i].Username = uname
+		if u := r.Data.Users[alias]; u != nil {
+			results[i].User = u.user()
+			continue
+		}
+
+		e, ok := aliasErrs[alias]
+		switch {
+		case !ok || e.Type == graphQLErrNotFound:
+			results[i].Err = ghsearch.ErrUserNotFound
+		default:
+			results[i].Err = fmt.Errorf("%w: %s", ghsearch.ErrUserSourceFailed, e.Message)
+		}
+	}
+	return results, nil
+}
+
+// NewGraphQLClient initializes GitHub GraphQL client and setup rate limits,
+// GraphQL API requires an access token.
+func NewGraphQLClient(accessToken string) (*GraphQLClient, error) {
+	if strings.TrimSpace(accessToken) == "" {
+		return nil, errors.New("access token required")
+	}
+
+	c := NewCustomGraphQLClient(APIBaseURL, accessToken, DefaultTimeout)
+	c.SetRetryPolicy(DefaultRetryPolicy)
+	r, err := c.client.requestRateLimitResources(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	c.rateLimiter.Set(*r.GraphQLRateLimit())
+	return c, nil
+}
+
+// NewCustomGraphQLClient creates new GitHub GraphQL client.
+func NewCustomGraphQLClient(url, accessToken string, timeout time.Duration) *GraphQLClient {
+	return &GraphQLClient{
+		client:      NewCustomClient(url, accessToken, timeout),
+		rateLimiter: NewRateLimiter(RateLimit{}),
+	}
+}
+
+const graphQLUserFields = `login name company followers { totalCount } ` +
+	`repositories(privacy: PUBLIC, ownerAffiliations: [OWNER]) { totalCount }`
+
+// graphQLRequest represents GraphQL request body.
+type graphQLRequest struct {
+	Query     string            `json:"query"`
+	Variables map[string]string `json:"variables"`
+}
+
+// newUsersQuery builds aliased user queries, usernames are passed as
+// variables so they are never interpolated into the query.
+func newUsersQuery(usernames []string) graphQLRequest {
+	vars := make(map[string]string, len(usernames))
+	params := make([]string, len(usernames))
+	fields := make([]string, len(usernames))
+	for i, uname := range usernames {
+		alias := userAlias(i)
+		vars[alias] = uname
+		params[i] = fmt.Sprintf("$%s: String!", alias)
+		fields[i] = fmt.Sprintf("%s: user(login: $%s) { %s }", alias, alias, graphQLUserFields)
+	}
+
+	q := fmt.Sprintf("query(%s) { %s rateLimit { limit remaining used resetAt } }",
+		strings.Join(params, ", "), strings.Join(fields, " "))
+	return graphQLRequest{q, vars}
+}
+
+func userAlias(i int) string {
+	return "u" + strconv.Itoa(i)
+}
+
+// graphQLUsersResponse represents aliased user queries response.
+type graphQLUsersResponse struct {
+	Data struct {
+		RateLimit *graphQLRateLimit
+		Users     map[string]*graphQLUser
+	}
+	Errors []graphQLError
+}
+
+func (r *graphQLUsersResponse) UnmarshalJSON(b []byte) error {
+	var raw struct {
+		Data   map[string]json.RawMessage
+		Errors []graphQLError
+	}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+
+	r.Errors = raw.Errors
+	r.Data.Users = make(map[string]*graphQLUser, len(raw.Data))
+	for key, val := range raw.Data {
+		if key == "rateLimit" {
+			if err := json.Unmarshal(val, &r.Data.RateLimit); err != nil {
+				return err
+			}
+			continue
+		}
+
+		var u *graphQLUser
+		if err := json.Unmarshal(val, &u); err != nil {
+			return err
+		}
+		r.Data.Users[key] = u
+	}
+	return nil
+}
+
+// batchError returns an error that failed the whole query.
+func (r *graphQLUsersResponse) batchError(resetsAt time.Time) error {
+	for _, e := range r.Errors {
+		if e.Type == graphQLErrRateLimited {
+			return ghsearch.NewRateLimitError(ErrRateLimitHit, resetsAt)
+		}
+		if len(e.Path) == 0 && len(r.Data.Users) == 0 {
+			return fmt.Errorf("%w: %s", ErrReqFailed, e.Message)
+		}
+	}
+	return nil
+}
+
+// aliasErrors returns errors indexed by alias of the user query they belong to.
+func (r *graphQLUsersResponse) aliasErrors() map[string]graphQLError {
+	errs := map[string]graphQLError{}
+	for _, e := range r.Errors {
+		if len(e.Path) == 0 {
+			continue
+		}
+		if alias, ok := e.Path[0].(string); ok {
+			errs[alias] = e
+		}
+	}
+	return errs
+}
+
+type graphQLError struct {
+	Type    string        `json:"type"`
+	Path    []interface{} `json:"path"`
+	Message string        `json:"message"`
+}
+
+type graphQLRateLimit struct {
+	Limit     int       `json:"limit"`
+	Remaining int       `json:"remaining"`
+	Used      int       `json:"used"`
+	ResetAt   time.Time `json:"resetAt"`
+}
+
+func (rl graphQLRateLimit) rateLimit() RateLimit {
+	return RateLimit{
+		Limit:     rl.Limit,
+		Remaining: rl.Remaining,
+		Used:      rl.Used,
+		ResetsAt:  rl.ResetAt,
+	}
+}
+
+type graphQLUser struct {
+	Login     string `json:"login"`
+	Name      string `json:"name"`
+	Company   string `json:"company"`
+	Followers struct {
+		TotalCount int `json:"totalCount"`
+	} `json:"followers"`
+	Repositories struct {
+		TotalCount int `json:"totalCount"`
+	} `json:"repositories"`
+}
+
+func (u graphQLUser) user() *ghsearch.User {
+	return &ghsearch.User{
+		Name:        u.Name,
+		Login:       u.Login,
+		Company:     u.Company,
+		Followers:   u.Followers.TotalCount,
+		PublicRepos: u.Repositories.TotalCount,
+	}
+}
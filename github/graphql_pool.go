@@ -0,0 +1,130 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kudarap/ghsearch"
+)
+
+// GraphQLPool represents GitHub GraphQL client that rotates between multiple
+// access tokens the same way as TokenPool, each token tracks its own GraphQL
+// rate limit which is separate from REST API.
+type GraphQLPool struct {
+	clients []*GraphQLClient
+
+	// mu guards client selection.
+	mu sync.Mutex
+}
+
+// User returns Github user details by username.
+func (p *GraphQLPool) User(ctx context.Context, username string) (*ghsearch.User, error) {
+	rr, err := p.Users(ctx, []string{username})
+	if err != nil {
+		return nil, err
+	}
+	return rr[0].User, rr[0].Err
+}
+
+// Users returns Github user details of usernames using the token with most
+// remaining rate limit. Caller access token on ctx is used instead of the pool tokens.
+func (p *GraphQLPool) Users(ctx context.Context, usernames []string) ([]ghsearch.UserResult, error) {
+	if ghsearch.SourceTokenFrom(ctx) != "" {
+		return p.clients[0].Users(ctx, usernames)
+	}
+
+	// Tries the next best token when the current one turns out to be exhausted.
+	tried := map[int]bool{}
+	for {
+		i, err := pickRateLimit(p.RateLimits(), tried)
+		if err != nil {
+			return nil, err
+		}
+
+		rr, err := p.clients[i].Users(ctx, usernames)
+		if errors.Is(err, ErrRateLimitHit) {
+			tried[i] = true
+			continue
+		}
+		return rr, err
+	}
+}
+
+// SetRateLimitMaxWait sets maximum duration to wait for rate limit reset on all tokens.
+func (p *GraphQLPool) SetRateLimitMaxWait(d time.Duration) {
+	for _, c := range p.clients {
+		c.SetRateLimitMaxWait(d)
+	}
+}
+
+// SetRateLimitStore shares GraphQL rate limit budget of all tokens with other instances.
+func (p *GraphQLPool) SetRateLimitStore(store RateLimitStore) {
+	for _, c := range p.clients {
+		c.SetRateLimitStore(store)
+	}
+}
+
+// SetRetryPolicy sets how transient request failures are retried on all tokens.
+func (p *GraphQLPool) SetRetryPolicy(rp RetryPolicy) {
+	for _, c := range p.clients {
+		c.SetRetryPolicy(rp)
+	}
+}
+
+// SetObserver enables instrumentation on all tokens.
+func (p *GraphQLPool) SetObserver(o Observer) {
+	for _, c := range p.clients {
+		c.SetObserver(o)
+	}
+}
+
+// RateLimitHeadroom requests current GraphQL rate limit of each token and
+// returns their combined headroom, fails only when none of them could be requested.
+func (p *GraphQLPool) RateLimitHeadroom(ctx context.Context) (*RateLimitHeadroom, error) {
+	requests := make([]func(context.Context) (*RateLimit, error), len(p.clients))
+	for i, c := range p.clients {
+		requests[i] = c.requestRateLimit
+	}
+	return requestHeadroom(ctx, requests)
+}
+
+// RateLimits returns current GraphQL rate limits of each token.
+func (p *GraphQLPool) RateLimits() []RateLimit {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	rr := make([]RateLimit, len(p.clients))
+	for i, c := range p.clients {
+		rr[i] = c.RateLimit()
+	}
+	return rr
+}
+
+// NewGraphQLPool initializes GitHub GraphQL clients for each access token and setup their rate limits.
+func NewGraphQLPool(accessTokens []string) (*GraphQLPool, error) {
+	var clients []*GraphQLClient
+	for _, t := range accessTokens {
+		if strings.TrimSpace(t) == "" {
+			continue
+		}
+
+		c, err := NewGraphQLClient(t)
+		if err != nil {
+			return nil, err
+		}
+		clients = append(clients, c)
+	}
+	if len(clients) == 0 {
+		return nil, errors.New("access token required")
+	}
+
+	return NewCustomGraphQLPool(clients...), nil
+}
+
+// NewCustomGraphQLPool creates new GraphQL token pool from GitHub GraphQL clients.
+func NewCustomGraphQLPool(clients ...*GraphQLClient) *GraphQLPool {
+	return &GraphQLPool{clients: clients}
+}
@@ -0,0 +1,67 @@
+package github_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/kudarap/ghsearch"
+	"github.com/kudarap/ghsearch/github"
+)
+
+func TestGraphQLPool_Users_Failover(t *testing.T) {
+	var exhaustedCalls int
+	exhausted := github.NewCustomGraphQLClient(newTestServer(func(w http.ResponseWriter, r *http.Request) {
+		exhaustedCalls++
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, rawRespBodyGraphQLRateLimited)
+	}).URL, "", 0)
+	exhausted.SetRateLimit(github.RateLimit{Limit: 5000, Remaining: 100})
+	available := github.NewCustomGraphQLClient(newTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, rawRespBodyGraphQLUsers)
+	}).URL, "", 0)
+	available.SetRateLimit(github.RateLimit{Limit: 5000, Remaining: 50})
+
+	pool := github.NewCustomGraphQLPool(exhausted, available)
+	got, err := pool.Users(context.Background(), []string{"kudarap", "n0b0dy"})
+	if err != nil {
+		t.Fatalf("err: %#v, want: nil", err)
+	}
+	if got[0].User == nil || got[0].User.Login != "kudarap" || got[1].Err != ghsearch.ErrUserNotFound {
+		t.Errorf("got: %#v", got)
+	}
+	if exhaustedCalls != 1 {
+		t.Errorf("exhausted calls: %d, want: %d", exhaustedCalls, 1)
+	}
+}
+
+func TestGraphQLPool_RateLimitHeadroom(t *testing.T) {
+	srv := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, `{"resources": {"graphql": {"limit": 5000, "remaining": 4000, "used": 1000, "reset": 1650230000}}}`)
+	})
+	defer srv.Close()
+	failedSrv := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprintf(w, rawRespBody404)
+	})
+	defer failedSrv.Close()
+
+	// Reports GraphQL resource and leaves out the failed token.
+	pool := github.NewCustomGraphQLPool(
+		github.NewCustomGraphQLClient(srv.URL, "", time.Second),
+		github.NewCustomGraphQLClient(failedSrv.URL, "", time.Second),
+	)
+	got, err := pool.RateLimitHeadroom(context.Background())
+	if err != nil {
+		t.Fatalf("err: %#v, want: nil", err)
+	}
+	want := &github.RateLimitHeadroom{Limit: 5000, Remaining: 4000, ResetsAt: time.Unix(1650230000, 0)}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("\ngot: \n\t%#v \nwant: \n\t%#v", got, want)
+	}
+}
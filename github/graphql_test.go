@@ -0,0 +1,171 @@
+package github_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/kudarap/ghsearch"
+	"github.com/kudarap/ghsearch/github"
+)
+
+func TestGraphQLClient_Users(t *testing.T) {
+	resetsAt := time.Date(2022, 4, 18, 0, 0, 0, 0, time.UTC)
+	testcases := []struct {
+		name string
+		// deps
+		testSrv *httptest.Server
+		// args
+		usernames []string
+		// returns
+		want          []ghsearch.UserResult
+		wantRateLimit github.RateLimit
+		wantErr       error
+	}{
+		{
+			"some not found",
+			newTestServer(func(w http.ResponseWriter, r *http.Request) {
+				var req struct {
+					Variables map[string]string
+				}
+				json.NewDecoder(r.Body).Decode(&req)
+				if req.Variables["u0"] != "kudarap" || req.Variables["u1"] != "n0b0dy" {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				w.WriteHeader(http.StatusOK)
+				fmt.Fprintf(w, rawRespBodyGraphQLUsers)
+			}),
+			[]string{"kudarap", "n0b0dy"},
+			[]ghsearch.UserResult{
+				{
+					Username: "kudarap",
+					User: &ghsearch.User{
+						Name:        "",
+						Login:       "kudarap",
+						Company:     "Openovate Labs",
+						Followers:   5,
+						PublicRepos: 38,
+					},
+				},
+				{Username: "n0b0dy", Err: ghsearch.ErrUserNotFound},
+			},
+			github.RateLimit{
+				Limit:     5000,
+				Remaining: 4998,
+				Used:      2,
+				ResetsAt:  resetsAt,
+			},
+			nil,
+		},
+		{
+			"rate limited",
+			newTestServer(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+				fmt.Fprintf(w, rawRespBodyGraphQLRateLimited)
+			}),
+			[]string{"kudarap"},
+			nil,
+			github.RateLimit{
+				Limit:     5000,
+				Remaining: 0,
+				Used:      5000,
+				ResetsAt:  resetsAt,
+			},
+			github.ErrRateLimitHit,
+		},
+		{
+			"internal error",
+			newTestServer(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusInternalServerError)
+				fmt.Fprintf(w, rawRespBody500)
+			}),
+			[]string{"kudarap"},
+			nil,
+			github.RateLimit{
+				Limit:     5000,
+				Remaining: 4999,
+				Used:      1,
+			},
+			ghsearch.ErrUserSourceFailed,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			gcl := github.NewCustomGraphQLClient(tc.testSrv.URL, "", 0)
+			gcl.SetRateLimit(github.RateLimit{Limit: 5000, Remaining: 5000})
+
+			got, gotErr := gcl.Users(context.Background(), tc.usernames)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("\ngot: \n\t%#v \nwant: \n\t%#v", got, tc.want)
+			}
+			if !reflect.DeepEqual(gcl.RateLimit(), tc.wantRateLimit) {
+				t.Errorf("\ngot: \n\t%#v \nwant: \n\t%#v", gcl.RateLimit(), tc.wantRateLimit)
+			}
+			if !errors.Is(gotErr, tc.wantErr) {
+				t.Errorf("err: %#v, want: %#v", gotErr, tc.wantErr)
+			}
+		})
+	}
+}
+
+const rawRespBodyGraphQLUsers = `{
+  "data": {
+    "u0": {
+      "login": "kudarap",
+      "name": null,
+      "company": "Openovate Labs",
+      "followers": {
+        "totalCount": 5
+      },
+      "repositories": {
+        "totalCount": 38
+      }
+    },
+    "u1": null,
+    "rateLimit": {
+      "limit": 5000,
+      "remaining": 4998,
+      "used": 2,
+      "resetAt": "2022-04-18T00:00:00Z"
+    }
+  },
+  "errors": [
+    {
+      "type": "NOT_FOUND",
+      "path": [
+        "u1"
+      ],
+      "locations": [
+        {
+          "line": 1,
+          "column": 230
+        }
+      ],
+      "message": "Could not resolve to a User with the login of 'n0b0dy'."
+    }
+  ]
+}`
+
+const rawRespBodyGraphQLRateLimited = `{
+  "data": {
+    "rateLimit": {
+      "limit": 5000,
+      "remaining": 0,
+      "used": 5000,
+      "resetAt": "2022-04-18T00:00:00Z"
+    }
+  },
+  "errors": [
+    {
+      "type": "RATE_LIMITED",
+      "message": "API rate limit exceeded for user ID 3943674."
+    }
+  ]
+}`
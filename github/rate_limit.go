@@ -2,10 +2,15 @@ package github
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"net/http"
 	"strconv"
+	"sync"
 	"time"
+
+	"github.com/kudarap/ghsearch"
 )
 
 // RateLimit represents github rate limit data.
@@ -16,32 +21,338 @@ type RateLimit struct {
 	ResetsAt  time.Time
 }
 
-// consume determines if we haven't reached the rate limit
-// and also checks if it's good to send again base on reset time.
-func (l *RateLimit) consume() error {
-	if !l.ResetsAt.IsZero() && l.ResetsAt.Before(time.Now()) {
-		return nil
+// available returns remaining rate limit considering reset time.
+func (l RateLimit) available(now time.Time) int {
+	if l.resetPassed(now) {
+		if l.Limit == 0 {
+			return 1
+		}
+		return l.Limit
 	}
-	if l.Remaining == 0 {
-		return ErrRateLimitHit
+	return l.Remaining
+}
+
+func (l RateLimit) resetPassed(now time.Time) bool {
+	return !l.ResetsAt.IsZero() && l.ResetsAt.Before(now)
+}
+
+// RateLimitStore represents a rate limit budget shared between service instances.
+type RateLimitStore interface {
+	// ConsumeRateLimit atomically takes a request from the shared budget, ok is
+	// false when its exhausted until resetsAt. Unknown or already reset budget
+	// is allowed since only the server can tell the new one.
+	ConsumeRateLimit(ctx context.Context, key string) (ok bool, resetsAt time.Time, err error)
+
+	// ReleaseRateLimit gives back a consumed request to the shared budget.
+	ReleaseRateLimit(ctx context.Context, key string) error
+
+	// UpdateRateLimit reconciles shared budget with the server rate limit.
+	UpdateRateLimit(ctx context.Context, key string, limit, remaining int, resetsAt time.Time) error
+}
+
+// RateLimiter represents a goroutine-safe rate limit accounting. It works like
+// a token bucket that refills on reset time and reconciled with the rate limit
+// reported by the server.
+type RateLimiter struct {
+	mu sync.Mutex
+	rl RateLimit
+
+	// maxWait sets how long to wait for rate limit to reset when exhausted,
+	// zero value fails immediately.
+	maxWait time.Duration
+
+	// store shares the budget with other instances when set, local rate limit
+	// is still used when the store is unavailable.
+	store    RateLimitStore
+	storeKey string
+}
+
+// RateLimit returns a snapshot of current rate limit.
+func (l *RateLimiter) RateLimit() RateLimit {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.rl
+}
+
+// Set replaces current rate limit.
+func (l *RateLimiter) Set(rl RateLimit) {
+	l.mu.Lock()
+	l.rl = rl
+	l.mu.Unlock()
+}
+
+// SetMaxWait sets maximum duration to block until rate limit resets.
+func (l *RateLimiter) SetMaxWait(d time.Duration) {
+	l.mu.Lock()
+	l.maxWait = d
+	l.mu.Unlock()
+}
+
+// SetStore shares rate limit budget under the key with other instances.
+func (l *RateLimiter) SetStore(store RateLimitStore, key string) {
+	l.mu.Lock()
+	l.store, l.storeKey = store, key
+	l.mu.Unlock()
+}
+
+// Wait consumes a request from the rate limit. When exhausted, it blocks until
+// reset when its within max wait and context deadline, otherwise returns an error.
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	for {
+		wait, err := l.consume(ctx)
+		if err == nil && wait == 0 {
+			wait, err = l.consumeShared(ctx)
+		}
+		if err != nil || wait == 0 {
+			return err
+		}
+
+		t := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		case <-t.C:
+		}
 	}
+}
 
-	l.Remaining--
-	l.Used++
-	return nil
+// consume takes a request from the rate limit when available,
+// returns how long to wait for reset otherwise.
+func (l *RateLimiter) consume(ctx context.Context) (wait time.Duration, err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if l.rl.resetPassed(now) {
+		// Unknown limit can't be accounted, allow until server tells otherwise.
+		if l.rl.Limit == 0 {
+			return 0, nil
+		}
+		l.rl = RateLimit{Limit: l.rl.Limit, Remaining: l.rl.Limit}
+	}
+	if l.rl.Remaining > 0 {
+		l.rl.Remaining--
+		l.rl.Used++
+		return 0, nil
+	}
+
+	return l.waitFor(ctx, now, l.rl.ResetsAt)
+}
+
+// consumeShared takes a request from the shared budget after the local one,
+// local request is given back when shared budget is exhausted.
+func (l *RateLimiter) consumeShared(ctx context.Context) (wait time.Duration, err error) {
+	l.mu.Lock()
+	store, key := l.store, l.storeKey
+	l.mu.Unlock()
+	if store == nil {
+		return 0, nil
+	}
+
+	ok, resetsAt, err := store.ConsumeRateLimit(ctx, key)
+	if err != nil || ok {
+		return 0, nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.releaseLocal()
+	return l.waitFor(ctx, time.Now(), resetsAt)
+}
+
+// waitFor returns how long to wait until reset when its allowed,
+// rate limit error otherwise.
+func (l *RateLimiter) waitFor(ctx context.Context, now, resetsAt time.Time) (time.Duration, error) {
+	rlErr := ghsearch.NewRateLimitError(ErrRateLimitHit, resetsAt)
+	if l.maxWait <= 0 || resetsAt.IsZero() {
+		return 0, rlErr
+	}
+	wait := resetsAt.Sub(now)
+	if wait > l.maxWait {
+		return 0, rlErr
+	}
+	if deadline, ok := ctx.Deadline(); ok && deadline.Before(resetsAt) {
+		return 0, rlErr
+	}
+	return wait, nil
 }
 
-func (l *RateLimit) updateFrom(h http.Header) {
-	hrl := rateLimitFrom(h)
-	if l.Remaining > hrl.Remaining {
+// Release gives back consumed request that did not count against rate limit.
+func (l *RateLimiter) Release(ctx context.Context) {
+	l.mu.Lock()
+	l.releaseLocal()
+	store, key := l.store, l.storeKey
+	l.mu.Unlock()
+
+	if store != nil {
+		_ = store.ReleaseRateLimit(ctx, key)
+	}
+}
+
+func (l *RateLimiter) releaseLocal() {
+	if l.rl.Used == 0 {
+		return
+	}
+	l.rl.Remaining++
+	l.rl.Used--
+}
+
+// Update reconciles current rate limit with the one reported by the server.
+// Responses of concurrent requests may arrive out of order, so lower remaining
+// is kept unless its from a newer rate limit window.
+func (l *RateLimiter) Update(ctx context.Context, rl RateLimit) {
+	l.mu.Lock()
+	if rl.ResetsAt.After(l.rl.ResetsAt) || rl.Remaining < l.rl.Remaining {
+		l.rl = rl
+	}
+	store, key := l.store, l.storeKey
+	l.mu.Unlock()
+
+	if store != nil {
+		_ = store.UpdateRateLimit(ctx, key, rl.Limit, rl.Remaining, rl.ResetsAt)
+	}
+}
+
+// updateFrom reconciles rate limit from response headers when present.
+func (l *RateLimiter) updateFrom(ctx context.Context, h http.Header) {
+	if h.Get(HeaderRateLimitRemaining) == "" {
 		return
 	}
-	*l = hrl
+	l.Update(ctx, rateLimitFrom(h))
+}
+
+// derive creates a new rate limiter with the same settings, budget is shared
+// under storeKey when store is set. Rate limit starts unknown and already
+// reset so requests are allowed until the server tells otherwise.
+func (l *RateLimiter) derive(storeKey string) *RateLimiter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return &RateLimiter{
+		rl:       RateLimit{ResetsAt: time.Unix(0, 0)},
+		maxWait:  l.maxWait,
+		store:    l.store,
+		storeKey: storeKey,
+	}
+}
+
+// NewRateLimiter creates new rate limiter with initial rate limit.
+func NewRateLimiter(rl RateLimit) *RateLimiter {
+	return &RateLimiter{rl: rl}
+}
+
+// responseRateLimited checks if the response was rejected due to rate limit.
+func responseRateLimited(r *http.Response) bool {
+	if r.StatusCode != http.StatusForbidden && r.StatusCode != http.StatusTooManyRequests {
+		return false
+	}
+	return r.Header.Get(HeaderRateLimitRemaining) == "0"
+}
+
+// RateLimit returns a snapshot of current rate limit.
+func (c *Client) RateLimit() RateLimit {
+	return c.rateLimiter.RateLimit()
+}
+
+// SetRateLimit replaces current rate limit.
+func (c *Client) SetRateLimit(rl RateLimit) {
+	c.rateLimiter.Set(rl)
+}
+
+// SetRateLimitMaxWait sets maximum duration to wait for rate limit reset
+// before sending a request, zero value fails immediately.
+func (c *Client) SetRateLimitMaxWait(d time.Duration) {
+	c.rateLimiter.SetMaxWait(d)
+}
+
+// SetRateLimitStore shares rate limit budget of the access token with other instances.
+func (c *Client) SetRateLimitStore(store RateLimitStore) {
+	c.rateLimiter.SetStore(store, rateLimitStoreKey("core", c.accessToken))
+}
+
+// rateLimitStoreKey identifies rate limit resource of an access token
+// without exposing the token itself.
+func rateLimitStoreKey(resource, accessToken string) string {
+	return resource + ":" + tokenHash(accessToken)
+}
+
+// tokenHash returns short hash of access token safe to use on keys.
+func tokenHash(accessToken string) string {
+	sum := sha256.Sum256([]byte(accessToken))
+	return hex.EncodeToString(sum[:8])
 }
 
 // RequestRateLimit returns current core rate limit.
 func (c *Client) RequestRateLimit() (*RateLimit, error) {
-	resp, err := c.getRequest(context.Background(), APIRateLimitEndpoint)
+	r, err := c.requestRateLimitResources(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	rl := r.RateLimit()
+	return rl, nil
+}
+
+// requestRateLimit requests current rate limit and updates tracked rate limit.
+func (c *Client) requestRateLimit(ctx context.Context) (*RateLimit, error) {
+	r, err := c.requestRateLimitResources(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rl := r.RateLimit()
+	c.rateLimiter.Update(ctx, *rl)
+	return rl, nil
+}
+
+// RateLimitHeadroom represents combined rate limit of access tokens.
+type RateLimitHeadroom struct {
+	Limit     int `json:"limit"`
+	Remaining int `json:"remaining"`
+	// ResetsAt is the earliest reset between the tokens.
+	ResetsAt time.Time `json:"resets_at"`
+}
+
+// requestHeadroom requests current rate limit of each token concurrently and
+// returns their combined headroom, tokens that failed the request are left out.
+// Returns the last error when none of them could be requested.
+func requestHeadroom(ctx context.Context, requests []func(context.Context) (*RateLimit, error)) (*RateLimitHeadroom, error) {
+	rr := make([]*RateLimit, len(requests))
+	errs := make([]error, len(requests))
+	var wg sync.WaitGroup
+	for i, request := range requests {
+		i, request := i, request
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rr[i], errs[i] = request(ctx)
+		}()
+	}
+	wg.Wait()
+
+	var h *RateLimitHeadroom
+	var err error
+	for i, rl := range rr {
+		if errs[i] != nil {
+			err = errs[i]
+			continue
+		}
+		if h == nil {
+			h = &RateLimitHeadroom{ResetsAt: rl.ResetsAt}
+		}
+		h.Limit += rl.Limit
+		h.Remaining += rl.Remaining
+		if rl.ResetsAt.Before(h.ResetsAt) {
+			h.ResetsAt = rl.ResetsAt
+		}
+	}
+	if h == nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+func (c *Client) requestRateLimitResources(ctx context.Context) (*RateLimitResponse, error) {
+	// Rate limit endpoint does not count against rate limit.
+	resp, err := c.getRequest(ctx, APIRateLimitEndpoint, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -53,8 +364,7 @@ func (c *Client) RequestRateLimit() (*RateLimit, error) {
 	if err = decodeBody(resp, &r); err != nil {
 		return nil, err
 	}
-	rl := r.RateLimit()
-	return rl, nil
+	return &r, nil
 }
 
 func (c *Client) acquireRateLimit() error {
@@ -67,30 +377,42 @@ func (c *Client) acquireRateLimit() error {
 		return errors.New("empty rate limit response")
 	}
 
-	c.RateLimit = *rl
+	c.rateLimiter.Set(*rl)
 	return nil
 }
 
 // RateLimitResponse represents Github's rate limit resource.
 type RateLimitResponse struct {
 	Resources struct {
-		Core struct {
-			Limit     int
-			Remaining int
-			Used      int
-			Reset     int64
-		}
+		Core    RateLimitResource
+		GraphQL RateLimitResource
 	}
 }
 
+// RateLimitResource represents rate limit of a single API resource.
+type RateLimitResource struct {
+	Limit     int
+	Remaining int
+	Used      int
+	Reset     int64
+}
+
 // RateLimit returns RateLimit details from a response.
 func (r RateLimitResponse) RateLimit() *RateLimit {
-	c := r.Resources.Core
+	return r.Resources.Core.rateLimit()
+}
+
+// GraphQLRateLimit returns GraphQL API RateLimit details from a response.
+func (r RateLimitResponse) GraphQLRateLimit() *RateLimit {
+	return r.Resources.GraphQL.rateLimit()
+}
+
+func (r RateLimitResource) rateLimit() *RateLimit {
 	return &RateLimit{
-		Limit:     c.Limit,
-		Remaining: c.Remaining,
-		Used:      c.Used,
-		ResetsAt:  time.Unix(c.Reset, 0),
+		Limit:     r.Limit,
+		Remaining: r.Remaining,
+		Used:      r.Used,
+		ResetsAt:  time.Unix(r.Reset, 0),
 	}
 }
 
@@ -2,11 +2,14 @@ package github_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
 	"strconv"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -53,7 +56,7 @@ func TestClient_RequestRateLimit(t *testing.T) {
 			if !reflect.DeepEqual(got, tc.want) {
 				t.Errorf("\ngot: \n\t%#v \nwant: \n\t%#v", got, tc.want)
 			}
-			if gotErr != tc.wantErr {
+			if !errors.Is(gotErr, tc.wantErr) {
 				t.Errorf("err: %#v, want: %#v", gotErr, tc.wantErr)
 			}
 		})
@@ -109,6 +112,30 @@ func TestClient_User_RateLimitCheck(t *testing.T) {
 			github.RateLimit{},
 			github.ErrRateLimitHit,
 		},
+		{
+			"rate limit reached on server",
+			newTestServer(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Add(github.HeaderRateLimitLimit, "60")
+				w.Header().Add(github.HeaderRateLimitRemaining, "0")
+				w.Header().Add(github.HeaderRateLimitUsed, "60")
+				w.Header().Add(github.HeaderRateLimitReset, strconv.FormatInt(now.Unix(), 10))
+				w.WriteHeader(http.StatusForbidden)
+				fmt.Fprintf(w, rawRespBody403RateLimit)
+			}),
+			github.RateLimit{
+				Limit:     60,
+				Remaining: 0,
+				Used:      60,
+				ResetsAt:  now.Add(-time.Minute),
+			},
+			github.RateLimit{
+				Limit:     60,
+				Remaining: 0,
+				Used:      60,
+				ResetsAt:  time.Unix(now.Unix(), 0),
+			},
+			github.ErrRateLimitHit,
+		},
 		{
 			"rate limit resets",
 			newTestServer(func(w http.ResponseWriter, r *http.Request) {
@@ -137,20 +164,212 @@ func TestClient_User_RateLimitCheck(t *testing.T) {
 	for _, tc := range testcases {
 		t.Run(tc.name, func(t *testing.T) {
 			client := github.NewCustomClient(tc.testSrv.URL, "", 0)
-			client.RateLimit = tc.current
+			client.SetRateLimit(tc.current)
 
 			ctx := context.Background()
 			_, gotErr := client.User(ctx, "kudarap")
-			if !reflect.DeepEqual(client.RateLimit, tc.want) {
-				t.Errorf("\ngot: \n\t%#v \nwant: \n\t%#v", client.RateLimit, tc.want)
+			if !reflect.DeepEqual(client.RateLimit(), tc.want) {
+				t.Errorf("\ngot: \n\t%#v \nwant: \n\t%#v", client.RateLimit(), tc.want)
+			}
+			if !errors.Is(gotErr, tc.wantErr) {
+				t.Errorf("err: %#v, want: %#v", gotErr, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestRateLimiter_Wait_Concurrent(t *testing.T) {
+	rl := github.NewRateLimiter(github.RateLimit{
+		Limit:     60,
+		Remaining: 50,
+		ResetsAt:  time.Now().Add(time.Hour),
+	})
+
+	var wg sync.WaitGroup
+	var failed int32
+	for i := 0; i < 60; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := rl.Wait(context.Background()); err != nil {
+				atomic.AddInt32(&failed, 1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	got := rl.RateLimit()
+	if got.Remaining != 0 || got.Used != 50 {
+		t.Errorf("remaining: %d used: %d, want: 0 and 50", got.Remaining, got.Used)
+	}
+	if failed != 10 {
+		t.Errorf("failed: %d, want: 10", failed)
+	}
+}
+
+func TestRateLimiter_Wait_Blocking(t *testing.T) {
+	testcases := []struct {
+		name string
+		// deps
+		resetsIn time.Duration
+		maxWait  time.Duration
+		// returns
+		wantErr error
+	}{
+		{
+			"fails immediately",
+			100 * time.Millisecond,
+			0,
+			github.ErrRateLimitHit,
+		},
+		{
+			"waits for reset",
+			100 * time.Millisecond,
+			time.Second,
+			nil,
+		},
+		{
+			"reset beyond max wait",
+			time.Minute,
+			time.Second,
+			github.ErrRateLimitHit,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			rl := github.NewRateLimiter(github.RateLimit{
+				Limit:     60,
+				Remaining: 0,
+				Used:      60,
+				ResetsAt:  time.Now().Add(tc.resetsIn),
+			})
+			rl.SetMaxWait(tc.maxWait)
+
+			gotErr := rl.Wait(context.Background())
+			if !errors.Is(gotErr, tc.wantErr) {
+				t.Errorf("err: %#v, want: %#v", gotErr, tc.wantErr)
 			}
-			if gotErr != tc.wantErr {
+		})
+	}
+}
+
+func TestRateLimiter_Update(t *testing.T) {
+	resetsAt := time.Unix(1650240000, 0)
+	testcases := []struct {
+		name string
+		// deps
+		current github.RateLimit
+		// args
+		update github.RateLimit
+		// returns
+		want github.RateLimit
+	}{
+		{
+			"lower remaining",
+			github.RateLimit{Limit: 60, Remaining: 50, Used: 10, ResetsAt: resetsAt},
+			github.RateLimit{Limit: 60, Remaining: 40, Used: 20, ResetsAt: resetsAt},
+			github.RateLimit{Limit: 60, Remaining: 40, Used: 20, ResetsAt: resetsAt},
+		},
+		{
+			"stale response",
+			github.RateLimit{Limit: 60, Remaining: 40, Used: 20, ResetsAt: resetsAt},
+			github.RateLimit{Limit: 60, Remaining: 50, Used: 10, ResetsAt: resetsAt},
+			github.RateLimit{Limit: 60, Remaining: 40, Used: 20, ResetsAt: resetsAt},
+		},
+		{
+			"new window",
+			github.RateLimit{Limit: 60, Remaining: 0, Used: 60, ResetsAt: resetsAt},
+			github.RateLimit{Limit: 60, Remaining: 59, Used: 1, ResetsAt: resetsAt.Add(time.Hour)},
+			github.RateLimit{Limit: 60, Remaining: 59, Used: 1, ResetsAt: resetsAt.Add(time.Hour)},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			rl := github.NewRateLimiter(tc.current)
+			rl.Update(context.Background(), tc.update)
+			if got := rl.RateLimit(); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("\ngot: \n\t%#v \nwant: \n\t%#v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRateLimiter_Wait_SharedStore(t *testing.T) {
+	resetsAt := time.Now().Add(time.Hour)
+	testcases := []struct {
+		name string
+		// deps
+		store *mockedRateLimitStore
+		// returns
+		wantRemaining int
+		wantErr       error
+	}{
+		{
+			"shared available",
+			&mockedRateLimitStore{remaining: 1, resetsAt: resetsAt},
+			59,
+			nil,
+		},
+		{
+			"shared exhausted",
+			&mockedRateLimitStore{remaining: 0, resetsAt: resetsAt},
+			60,
+			github.ErrRateLimitHit,
+		},
+		{
+			"shared unavailable",
+			&mockedRateLimitStore{err: errors.New("connection refused")},
+			59,
+			nil,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			rl := github.NewRateLimiter(github.RateLimit{
+				Limit:     60,
+				Remaining: 60,
+				ResetsAt:  resetsAt,
+			})
+			rl.SetStore(tc.store, "core:test")
+
+			gotErr := rl.Wait(context.Background())
+			if got := rl.RateLimit().Remaining; got != tc.wantRemaining {
+				t.Errorf("remaining: %d, want: %d", got, tc.wantRemaining)
+			}
+			if !errors.Is(gotErr, tc.wantErr) {
 				t.Errorf("err: %#v, want: %#v", gotErr, tc.wantErr)
 			}
 		})
 	}
 }
 
+type mockedRateLimitStore struct {
+	remaining int
+	resetsAt  time.Time
+	err       error
+}
+
+func (m *mockedRateLimitStore) ConsumeRateLimit(ctx context.Context, key string) (bool, time.Time, error) {
+	if m.err != nil {
+		return false, time.Time{}, m.err
+	}
+	if m.remaining == 0 {
+		return false, m.resetsAt, nil
+	}
+	m.remaining--
+	return true, m.resetsAt, nil
+}
+
+func (m *mockedRateLimitStore) ReleaseRateLimit(ctx context.Context, key string) error {
+	m.remaining++
+	return m.err
+}
+
+func (m *mockedRateLimitStore) UpdateRateLimit(ctx context.Context, key string, limit, remaining int, resetsAt time.Time) error {
+	m.remaining, m.resetsAt = remaining, resetsAt
+	return m.err
+}
+
 const rawRespBodyRateLimit = `{
   "resources": {
     "core": {
@@ -0,0 +1,185 @@
+package github
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HeaderRetryAfter is a response header key for how long to wait before retrying.
+const HeaderRetryAfter = "retry-after"
+
+// RetryPolicy represents how transient request failures are retried.
+// Not found and rate limited responses are never retried.
+type RetryPolicy struct {
+	// MaxAttempts includes the first request, one or less disables retry.
+	MaxAttempts int
+
+	// BaseDelay doubles on each attempt up to MaxDelay.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+
+	// Jitter randomly reduces delay by this fraction between 0 and 1
+	// to spread retries of concurrent requests.
+	Jitter float64
+
+	// RetryableStatuses are response status codes considered transient,
+	// network errors are always retryable but client timeouts never are.
+	RetryableStatuses []int
+
+	// MaxElapsed caps total time of a request including retries, a retry is
+	// only sent when its delay and a whole attempt timeout still fits. Zero
+	// only limits retries by the request deadline.
+	MaxElapsed time.Duration
+}
+
+// DefaultRetryPolicy retries server errors twice within twice the default timeout.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   100 * time.Millisecond,
+	MaxDelay:    time.Second,
+	Jitter:      0.5,
+	MaxElapsed:  2 * DefaultTimeout,
+	RetryableStatuses: []int{
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout,
+	},
+}
+
+// retryable checks if the request failure is transient.
+func (p RetryPolicy) retryable(ctx context.Context, resp *http.Response, err error) bool {
+	if err != nil {
+		// Caller gave up, no point on retrying. Timed out attempt already
+		// took a whole timeout and retrying a slow source only adds load.
+		return ctx.Err() == nil && !os.IsTimeout(err)
+	}
+	if resp.StatusCode == http.StatusNotFound ||
+		resp.StatusCode == http.StatusTooManyRequests ||
+		responseRateLimited(resp) {
+		return false
+	}
+	for _, s := range p.RetryableStatuses {
+		if resp.StatusCode == s {
+			return true
+		}
+	}
+	return false
+}
+
+// delay returns backoff duration of attempt, Retry-After response header
+// takes precedence when its longer.
+func (p RetryPolicy) delay(attempt int, resp *http.Response) time.Duration {
+	d := p.BaseDelay << (attempt - 1)
+	if d > p.MaxDelay || d <= 0 {
+		d = p.MaxDelay
+	}
+	if p.Jitter > 0 {
+		d -= time.Duration(rand.Float64() * p.Jitter * float64(d))
+	}
+	if resp != nil {
+		if ra := retryAfterFrom(resp.Header); ra > d {
+			d = ra
+		}
+	}
+	return d
+}
+
+// doRequest sends request created by newReq and retries transient failures
+// according to retry policy as long as the context deadline allows. Each retry
+// spends quota the same as the first attempt, so it takes another request from
+// rl after reconciling it with the failed response. Nil rl is not accounted.
+func (c *Client) doRequest(ctx context.Context, rl *RateLimiter, newReq func() (*http.Request, error)) (*http.Response, error) {
+	p := c.retryPolicy
+	start := time.Now()
+	for attempt := 1; ; attempt++ {
+		req, err := newReq()
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := c.httpClient.Do(req)
+		c.observeRequest(req, resp)
+		if attempt >= p.MaxAttempts || !p.retryable(ctx, resp, err) {
+			return resp, err
+		}
+		delay := p.delay(attempt, resp)
+		if !c.retryFits(ctx, start, delay) {
+			return resp, err
+		}
+		if resp != nil {
+			if rl != nil {
+				rl.updateFrom(ctx, resp.Header)
+			}
+			// Drains body to reuse the connection.
+			_, _ = io.Copy(ioutil.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		t := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return nil, ctx.Err()
+		case <-t.C:
+		}
+		if rl != nil {
+			if err = rl.Wait(ctx); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+// retryFits checks if another attempt after delay can finish within
+// request deadline and retry policy max elapsed.
+func (c *Client) retryFits(ctx context.Context, start time.Time, delay time.Duration) bool {
+	end := time.Now().Add(delay + c.httpClient.Timeout)
+	if deadline, ok := ctx.Deadline(); ok && end.After(deadline) {
+		return false
+	}
+	return c.retryPolicy.MaxElapsed <= 0 || !end.After(start.Add(c.retryPolicy.MaxElapsed))
+}
+
+func (c *Client) observeRequest(req *http.Request, resp *http.Response) {
+	if c.observer == nil {
+		return
+	}
+
+	api := "rest"
+	if strings.HasSuffix(req.URL.Path, APIGraphQLEndpoint) {
+		api = "graphql"
+	}
+	var status int
+	if resp != nil {
+		status = resp.StatusCode
+	}
+	c.observer.GithubRequest(api, status)
+}
+
+// SetRetryPolicy sets how transient request failures are retried.
+func (c *Client) SetRetryPolicy(p RetryPolicy) {
+	c.retryPolicy = p
+}
+
+// retryAfterFrom parses Retry-After header in seconds or HTTP date format.
+func retryAfterFrom(h http.Header) time.Duration {
+	v := h.Get(HeaderRetryAfter)
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return time.Until(t)
+	}
+	return 0
+}
@@ -0,0 +1,223 @@
+package github_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kudarap/ghsearch"
+	"github.com/kudarap/ghsearch/github"
+)
+
+func TestClient_User_Retry(t *testing.T) {
+	policy := github.RetryPolicy{
+		MaxAttempts:       3,
+		BaseDelay:         10 * time.Millisecond,
+		MaxDelay:          50 * time.Millisecond,
+		RetryableStatuses: []int{http.StatusBadGateway, http.StatusServiceUnavailable},
+	}
+	testcases := []struct {
+		name string
+		// deps
+		status     int
+		failures   int
+		retryAfter string
+		timeout    time.Duration
+		// returns
+		wantAttempts int
+		wantErr      error
+	}{
+		{
+			"recovers",
+			http.StatusBadGateway,
+			2,
+			"",
+			0,
+			3,
+			nil,
+		},
+		{
+			"exhausted attempts",
+			http.StatusServiceUnavailable,
+			5,
+			"",
+			0,
+			3,
+			ghsearch.ErrUserSourceFailed,
+		},
+		{
+			"not found never retried",
+			http.StatusNotFound,
+			5,
+			"",
+			0,
+			1,
+			ghsearch.ErrUserNotFound,
+		},
+		{
+			"rate limited never retried",
+			http.StatusTooManyRequests,
+			5,
+			"",
+			0,
+			1,
+			ghsearch.ErrUserSourceFailed,
+		},
+		{
+			"non retryable status",
+			http.StatusInternalServerError,
+			5,
+			"",
+			0,
+			1,
+			ghsearch.ErrUserSourceFailed,
+		},
+		{
+			"retry after beyond deadline",
+			http.StatusServiceUnavailable,
+			5,
+			"10",
+			time.Second,
+			1,
+			ghsearch.ErrUserSourceFailed,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			var attempts int
+			testSrv := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+				attempts++
+				if attempts <= tc.failures {
+					if tc.retryAfter != "" {
+						w.Header().Set(github.HeaderRetryAfter, tc.retryAfter)
+					}
+					w.WriteHeader(tc.status)
+					fmt.Fprintf(w, rawRespBody500)
+					return
+				}
+				w.WriteHeader(http.StatusOK)
+				fmt.Fprintf(w, rawRespBodyUser)
+			})
+
+			ctx := context.Background()
+			if tc.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tc.timeout)
+				defer cancel()
+			}
+			gcl := github.NewCustomClient(testSrv.URL, "", 0)
+			gcl.SetRateLimit(github.RateLimit{Limit: 60, Remaining: 60, ResetsAt: time.Now().Add(time.Hour)})
+			gcl.SetRetryPolicy(policy)
+
+			_, gotErr := gcl.User(ctx, "kudarap")
+			if attempts != tc.wantAttempts {
+				t.Errorf("attempts: %d, want: %d", attempts, tc.wantAttempts)
+			}
+			// Every attempt spends quota.
+			if got, want := gcl.RateLimit().Remaining, 60-tc.wantAttempts; got != want {
+				t.Errorf("remaining: %d, want: %d", got, want)
+			}
+			if !errors.Is(gotErr, tc.wantErr) {
+				t.Errorf("err: %#v, want: %#v", gotErr, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestClient_User_RetryBudget(t *testing.T) {
+	testcases := []struct {
+		name string
+		// deps
+		respDelay  time.Duration
+		timeout    time.Duration
+		maxElapsed time.Duration
+		// returns
+		wantAttempts int32
+	}{
+		{"client timeout never retried", 100 * time.Millisecond, 50 * time.Millisecond, 0, 1},
+		{"within max elapsed", 0, time.Second, 3 * time.Second, 3},
+		{"attempt beyond max elapsed", 0, time.Second, 900 * time.Millisecond, 1},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			var attempts int32
+			testSrv := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&attempts, 1)
+				time.Sleep(tc.respDelay)
+				w.WriteHeader(http.StatusServiceUnavailable)
+				fmt.Fprintf(w, rawRespBody500)
+			})
+			defer testSrv.Close()
+
+			gcl := github.NewCustomClient(testSrv.URL, "", tc.timeout)
+			gcl.SetRateLimit(github.RateLimit{Limit: 60, Remaining: 60})
+			gcl.SetRetryPolicy(github.RetryPolicy{
+				MaxAttempts:       3,
+				BaseDelay:         10 * time.Millisecond,
+				MaxDelay:          10 * time.Millisecond,
+				RetryableStatuses: []int{http.StatusServiceUnavailable},
+				MaxElapsed:        tc.maxElapsed,
+			})
+
+			gcl.User(context.Background(), "kudarap")
+			if got := atomic.LoadInt32(&attempts); got != tc.wantAttempts {
+				t.Errorf("attempts: %d, want: %d", got, tc.wantAttempts)
+			}
+		})
+	}
+}
+
+func TestClient_User_Observer(t *testing.T) {
+	var attempts int
+	testSrv := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			fmt.Fprintf(w, rawRespBody500)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, rawRespBodyUser)
+	})
+	defer testSrv.Close()
+
+	obs := &mockedObserver{requests: map[string]int{}}
+	gcl := github.NewCustomClient(testSrv.URL, "", 0)
+	gcl.SetRateLimit(github.RateLimit{Limit: 60, Remaining: 60})
+	gcl.SetRetryPolicy(github.RetryPolicy{MaxAttempts: 2, RetryableStatuses: []int{http.StatusBadGateway}})
+	gcl.SetObserver(obs)
+	if _, err := gcl.User(context.Background(), "kudarap"); err != nil {
+		t.Fatalf("err: %#v, want: nil", err)
+	}
+
+	want := map[string]int{"rest 502": 1, "rest 200": 1}
+	if !reflect.DeepEqual(obs.requests, want) {
+		t.Errorf("\ngot: \n\t%#v \nwant: \n\t%#v", obs.requests, want)
+	}
+}
+
+type mockedObserver struct {
+	mu       sync.Mutex
+	requests map[string]int
+	deduped  map[string]int
+}
+
+func (o *mockedObserver) GithubRequest(api string, status int) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.requests[fmt.Sprintf("%s %d", api, status)]++
+}
+
+func (o *mockedObserver) RequestDeduped(group string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.deduped != nil {
+		o.deduped[group]++
+	}
+}
@@ -0,0 +1,193 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kudarap/ghsearch"
+	"golang.org/x/sync/singleflight"
+)
+
+// TokenPool represents GitHub client that rotates between multiple access tokens
+// to increase available rate limits. Each token tracks its own rate limit and
+// exhausted tokens are set aside until their rate limit resets.
+type TokenPool struct {
+	clients []*Client
+
+	// mu guards client selection.
+	mu sync.Mutex
+
+	// requestGroup to prevent duplicate in-flight requests across tokens
+	requestGroup singleflight.Group
+
+	// observer receives pool events for instrumentation, disabled when nil.
+	observer Observer
+
+	// callers keeps clients of caller supplied access tokens.
+	callers callerClients
+}
+
+// User returns Github user details by username using the token with most remaining rate limit.
+// Caller access token on ctx is used instead of the pool tokens.
+func (p *TokenPool) User(ctx context.Context, username string) (*ghsearch.User, error) {
+	// Not grouped with pool requests since the caller token may be rejected.
+	if token := ghsearch.SourceTokenFrom(ctx); token != "" {
+		return p.callerClient(token).User(ctx, username)
+	}
+
+	// avoid duplicate inflight requests.
+	var leader bool
+	v, err, _ := p.requestGroup.Do(username, func() (interface{}, error) {
+		leader = true
+		return p.fetchUser(ctx, username)
+	})
+	if !leader && p.observer != nil {
+		p.observer.RequestDeduped("github_pool")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return v.(*ghsearch.User), nil
+}
+
+// callerClient returns client of caller token with the same settings as pool tokens.
+func (p *TokenPool) callerClient(token string) *Client {
+	return p.callers.get(token, func(token string) interface{} {
+		return p.clients[0].withToken(token)
+	}).(*Client)
+}
+
+func (p *TokenPool) fetchUser(ctx context.Context, username string) (*ghsearch.User, error) {
+	// Tries the next best token when the current one turns out to be exhausted.
+	tried := map[int]bool{}
+	for {
+		i, err := pickRateLimit(p.RateLimits(), tried)
+		if err != nil {
+			return nil, err
+		}
+
+		u, err := p.clients[i].User(ctx, username)
+		if errors.Is(err, ErrRateLimitHit) {
+			tried[i] = true
+			continue
+		}
+		return u, err
+	}
+}
+
+// pickRateLimit selects index of the rate limit with most remaining,
+// returns rate limit error with the earliest reset when all are exhausted.
+func pickRateLimit(rr []RateLimit, exclude map[int]bool) (int, error) {
+	now := time.Now()
+	best := -1
+	var bestRemaining int
+	var resetsAt time.Time
+	for i, rl := range rr {
+		if exclude[i] {
+			continue
+		}
+
+		remaining := rl.available(now)
+		if remaining == 0 {
+			if resetsAt.IsZero() || rl.ResetsAt.Before(resetsAt) {
+				resetsAt = rl.ResetsAt
+			}
+			continue
+		}
+		if best == -1 || remaining > bestRemaining {
+			best, bestRemaining = i, remaining
+		}
+	}
+	if best == -1 {
+		return -1, ghsearch.NewRateLimitError(ErrRateLimitHit, resetsAt)
+	}
+	return best, nil
+}
+
+// SetValidatorCache enables conditional requests on all tokens.
+func (p *TokenPool) SetValidatorCache(vc Cache) {
+	for _, c := range p.clients {
+		c.SetValidatorCache(vc)
+	}
+}
+
+// SetRateLimitMaxWait sets maximum duration to wait for rate limit reset on all tokens.
+func (p *TokenPool) SetRateLimitMaxWait(d time.Duration) {
+	for _, c := range p.clients {
+		c.SetRateLimitMaxWait(d)
+	}
+}
+
+// SetRateLimitStore shares rate limit budget of all tokens with other instances.
+func (p *TokenPool) SetRateLimitStore(store RateLimitStore) {
+	for _, c := range p.clients {
+		c.SetRateLimitStore(store)
+	}
+}
+
+// SetRetryPolicy sets how transient request failures are retried on all tokens.
+func (p *TokenPool) SetRetryPolicy(rp RetryPolicy) {
+	for _, c := range p.clients {
+		c.SetRetryPolicy(rp)
+	}
+}
+
+// SetObserver enables instrumentation on the pool and all tokens.
+func (p *TokenPool) SetObserver(o Observer) {
+	p.observer = o
+	for _, c := range p.clients {
+		c.SetObserver(o)
+	}
+}
+
+// RateLimitHeadroom requests current rate limit of each token and returns
+// their combined headroom, fails only when none of them could be requested.
+func (p *TokenPool) RateLimitHeadroom(ctx context.Context) (*RateLimitHeadroom, error) {
+	requests := make([]func(context.Context) (*RateLimit, error), len(p.clients))
+	for i, c := range p.clients {
+		requests[i] = c.requestRateLimit
+	}
+	return requestHeadroom(ctx, requests)
+}
+
+// RateLimits returns current rate limits of each token.
+func (p *TokenPool) RateLimits() []RateLimit {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	rr := make([]RateLimit, len(p.clients))
+	for i, c := range p.clients {
+		rr[i] = c.RateLimit()
+	}
+	return rr
+}
+
+// NewTokenPool initializes GitHub clients for each access token and setup their rate limits.
+func NewTokenPool(accessTokens []string) (*TokenPool, error) {
+	var clients []*Client
+	for _, t := range accessTokens {
+		if strings.TrimSpace(t) == "" {
+			continue
+		}
+
+		c, err := NewClient(t)
+		if err != nil {
+			return nil, err
+		}
+		clients = append(clients, c)
+	}
+	if len(clients) == 0 {
+		return nil, errors.New("access token required")
+	}
+
+	return NewCustomTokenPool(clients...), nil
+}
+
+// NewCustomTokenPool creates new token pool from GitHub clients.
+func NewCustomTokenPool(clients ...*Client) *TokenPool {
+	return &TokenPool{clients: clients}
+}
@@ -0,0 +1,264 @@
+package github_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/kudarap/ghsearch"
+	"github.com/kudarap/ghsearch/github"
+)
+
+func TestTokenPool_User(t *testing.T) {
+	resetsAt := time.Now().Add(time.Hour)
+	testcases := []struct {
+		name string
+		// client states
+		rateLimits []github.RateLimit
+		// returns
+		wantClient   int
+		wantErr      error
+		wantResetsAt time.Time
+	}{
+		{
+			"most remaining",
+			[]github.RateLimit{
+				{Limit: 5000, Remaining: 10, ResetsAt: resetsAt},
+				{Limit: 5000, Remaining: 20, ResetsAt: resetsAt},
+			},
+			1,
+			nil,
+			time.Time{},
+		},
+		{
+			"exhausted set aside",
+			[]github.RateLimit{
+				{Limit: 5000, Remaining: 0, ResetsAt: resetsAt},
+				{Limit: 5000, Remaining: 1, ResetsAt: resetsAt},
+			},
+			1,
+			nil,
+			time.Time{},
+		},
+		{
+			"exhausted resets",
+			[]github.RateLimit{
+				{Limit: 5000, Remaining: 0, ResetsAt: time.Now().Add(-time.Minute)},
+				{Limit: 5000, Remaining: 1, ResetsAt: resetsAt},
+			},
+			0,
+			nil,
+			time.Time{},
+		},
+		{
+			"all exhausted",
+			[]github.RateLimit{
+				{Limit: 5000, Remaining: 0, ResetsAt: resetsAt.Add(time.Minute)},
+				{Limit: 5000, Remaining: 0, ResetsAt: resetsAt},
+			},
+			-1,
+			github.ErrRateLimitHit,
+			resetsAt,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			gotClient := -1
+			var clients []*github.Client
+			for i, rl := range tc.rateLimits {
+				i := i
+				c := github.NewCustomClient(newTestServer(func(w http.ResponseWriter, r *http.Request) {
+					gotClient = i
+					w.WriteHeader(http.StatusOK)
+					fmt.Fprintf(w, rawRespBodyUser)
+				}).URL, "", 0)
+				c.SetRateLimit(rl)
+				clients = append(clients, c)
+			}
+
+			pool := github.NewCustomTokenPool(clients...)
+			_, gotErr := pool.User(context.Background(), "kudarap")
+			if gotClient != tc.wantClient {
+				t.Errorf("client: %d, want: %d", gotClient, tc.wantClient)
+			}
+			if !errors.Is(gotErr, tc.wantErr) {
+				t.Errorf("err: %#v, want: %#v", gotErr, tc.wantErr)
+			}
+			var rle *ghsearch.RateLimitError
+			if errors.As(gotErr, &rle) && !rle.ResetsAt.Equal(tc.wantResetsAt) {
+				t.Errorf("resets at: %s, want: %s", rle.ResetsAt, tc.wantResetsAt)
+			}
+		})
+	}
+}
+
+func TestTokenPool_User_Failover(t *testing.T) {
+	exhausted := github.NewCustomClient(newTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add(github.HeaderRateLimitLimit, "5000")
+		w.Header().Add(github.HeaderRateLimitRemaining, "0")
+		w.Header().Add(github.HeaderRateLimitUsed, "5000")
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprintf(w, rawRespBody403RateLimit)
+	}).URL, "", 0)
+	exhausted.SetRateLimit(github.RateLimit{Limit: 5000, Remaining: 100})
+	available := github.NewCustomClient(newTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, rawRespBodyUser)
+	}).URL, "", 0)
+	available.SetRateLimit(github.RateLimit{Limit: 5000, Remaining: 50})
+
+	pool := github.NewCustomTokenPool(exhausted, available)
+	got, err := pool.User(context.Background(), "kudarap")
+	if err != nil {
+		t.Fatalf("err: %#v, want: nil", err)
+	}
+	if got.Login != "kudarap" {
+		t.Errorf("login: %s, want: kudarap", got.Login)
+	}
+}
+
+func TestTokenPool_User_CallerToken(t *testing.T) {
+	var gotAuth string
+	srv := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		if gotAuth != "token caller" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Add(github.HeaderRateLimitLimit, "5000")
+		w.Header().Add(github.HeaderRateLimitRemaining, "4999")
+		w.Header().Add(github.HeaderRateLimitReset, fmt.Sprint(time.Now().Add(time.Hour).Unix()))
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, rawRespBodyUser)
+	})
+	service := github.NewCustomClient(srv.URL, "service", 0)
+	service.SetRateLimit(github.RateLimit{Limit: 5000, Remaining: 100})
+	pool := github.NewCustomTokenPool(service)
+
+	ctx := ghsearch.WithSourceToken(context.Background(), "caller")
+	if _, err := pool.User(ctx, "kudarap"); err != nil {
+		t.Fatalf("err: %#v, want: nil", err)
+	}
+	if gotAuth != "token caller" {
+		t.Errorf("authorization: %s, want: token caller", gotAuth)
+	}
+	// Caller rate limit is tracked separately.
+	if rl := service.RateLimit(); rl.Remaining != 100 {
+		t.Errorf("service remaining: %d, want: 100", rl.Remaining)
+	}
+
+	ctx = ghsearch.WithSourceToken(context.Background(), "revoked")
+	_, err := pool.User(ctx, "kudarap")
+	if !errors.Is(err, ghsearch.ErrUserSourceUnauthorized) {
+		t.Errorf("err: %#v, want: %#v", err, ghsearch.ErrUserSourceUnauthorized)
+	}
+}
+
+func TestTokenPool_User_CallerTokenRejected(t *testing.T) {
+	testcases := []struct {
+		name string
+		// args
+		token  string
+		status int
+		// returns
+		wantErr error
+	}{
+		{"caller bad credentials", "caller", http.StatusUnauthorized, github.ErrBadCredentials},
+		{"caller forbidden", "caller", http.StatusForbidden, github.ErrTokenForbidden},
+		{"service bad credentials", "", http.StatusUnauthorized, ghsearch.ErrUserSourceFailed},
+		{"service forbidden", "", http.StatusForbidden, ghsearch.ErrUserSourceFailed},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tc.status)
+			})
+			service := github.NewCustomClient(srv.URL, "service", 0)
+			service.SetRateLimit(github.RateLimit{Limit: 5000, Remaining: 100})
+			cb := ghsearch.NewCircuitBreaker(github.NewCustomTokenPool(service), ghsearch.DefaultCircuitBreakerConfig)
+
+			ctx := context.Background()
+			if tc.token != "" {
+				ctx = ghsearch.WithSourceToken(ctx, tc.token)
+			}
+			_, err := cb.User(ctx, "kudarap")
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("err: %#v, want: %#v", err, tc.wantErr)
+			}
+
+			// Only service token rejection counts against the source.
+			wantFailures := 0
+			if tc.token == "" {
+				wantFailures = 1
+			}
+			if got := cb.Status().Failures; got != wantFailures {
+				t.Errorf("failures: %d, want: %d", got, wantFailures)
+			}
+		})
+	}
+}
+
+func TestTokenPool_RateLimitHeadroom(t *testing.T) {
+	okSrv := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, rawRespBodyRateLimit)
+	})
+	defer okSrv.Close()
+	exhaustedSrv := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, `{"resources": {"core": {"limit": 60, "remaining": 0, "used": 60, "reset": 1650230000}}}`)
+	})
+	defer exhaustedSrv.Close()
+	failedSrv := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprintf(w, rawRespBody404)
+	})
+	defer failedSrv.Close()
+
+	testcases := []struct {
+		name string
+		// deps
+		urls []string
+		// returns
+		want    *github.RateLimitHeadroom
+		wantErr error
+	}{
+		{
+			"failed token left out",
+			[]string{failedSrv.URL, okSrv.URL, okSrv.URL},
+			&github.RateLimitHeadroom{Limit: 120, Remaining: 120, ResetsAt: time.Unix(1650240000, 0)},
+			nil,
+		},
+		{
+			"exhausted token is not an error",
+			[]string{exhaustedSrv.URL, okSrv.URL},
+			&github.RateLimitHeadroom{Limit: 120, Remaining: 60, ResetsAt: time.Unix(1650230000, 0)},
+			nil,
+		},
+		{
+			"all tokens failed",
+			[]string{failedSrv.URL, failedSrv.URL},
+			nil,
+			github.ErrReqFailed,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			var clients []*github.Client
+			for _, u := range tc.urls {
+				clients = append(clients, github.NewCustomClient(u, "", time.Second))
+			}
+			got, err := github.NewCustomTokenPool(clients...).RateLimitHeadroom(context.Background())
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("err: %#v, want: %#v", err, tc.wantErr)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("\ngot: \n\t%#v \nwant: \n\t%#v", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package github
+
+import (
+	"errors"
+
+	"github.com/kudarap/ghsearch"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer returns tracer of the global tracer provider at the time of use
+// so a replaced provider takes effect, spans are not recorded until its set.
+func tracer() trace.Tracer {
+	return otel.Tracer("github.com/kudarap/ghsearch/github")
+}
+
+// traceResponse annotates span with response status and rate limit after the response.
+func traceResponse(span trace.Span, status int, rl RateLimit) {
+	span.SetAttributes(
+		semconv.HTTPStatusCodeKey.Int(status),
+		attribute.Int("github.rate_limit.limit", rl.Limit),
+		attribute.Int("github.rate_limit.remaining", rl.Remaining),
+		attribute.Int64("github.rate_limit.resets_at", rl.ResetsAt.Unix()),
+	)
+}
+
+// endSpan ends span and records error, not found is an expected outcome.
+func endSpan(span trace.Span, err error) {
+	if err != nil && !errors.Is(err, ghsearch.ErrUserNotFound) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -4,17 +4,38 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"net/url"
 	"os"
+	"time"
 
 	"github.com/kudarap/ghsearch"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// validatorCacheExpr sets how long user validators can be used for revalidation.
+	validatorCacheExpr = 24 * time.Hour
+
+	// validatorCacheKeyPrefix uses colon since its not allowed on usernames
+	// and won't collide with other user keys. Keys are scoped by access token
+	// since GitHub validators vary by Authorization.
+	validatorCacheKeyPrefix = "github:user:"
 )
 
 // User returns Github user details by username.
 func (c *Client) User(ctx context.Context, username string) (*ghsearch.User, error) {
 	// avoid duplicate inflight requests.
+	// shared is also true for the caller that made the request,
+	// only callers that joined it are counted.
+	var leader bool
 	v, err, _ := c.requestGroup.Do(username, func() (interface{}, error) {
+		leader = true
 		return c.fetchUser(ctx, username)
 	})
+	if !leader && c.observer != nil {
+		c.observer.RequestDeduped("github")
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -22,23 +43,53 @@ func (c *Client) User(ctx context.Context, username string) (*ghsearch.User, err
 	return v.(*ghsearch.User), nil
 }
 
-func (c *Client) fetchUser(ctx context.Context, username string) (*ghsearch.User, error) {
-	if err := c.RateLimit.consume(); err != nil {
+func (c *Client) fetchUser(ctx context.Context, username string) (user *ghsearch.User, err error) {
+	ctx, span := tracer().Start(ctx, "github.Client.User", trace.WithAttributes(
+		attribute.String("github.username", username),
+	))
+	defer func() { endSpan(span, err) }()
+
+	if err := c.rateLimiter.Wait(ctx); err != nil {
 		return nil, err
 	}
 
-	url := fmt.Sprintf("%s/%s", APIUserEndpoint, username)
-	resp, err := c.getRequest(ctx, url)
+	// Revalidate previous response when available, not modified
+	// responses does not count against rate limit.
+	prev := c.cachedUser(ctx, username)
+	var vals *validators
+	if prev != nil {
+		vals = &prev.validators
+	}
+
+	// Escaped so usernames cant be read as other endpoints or query.
+	path := fmt.Sprintf("%s/%s", APIUserEndpoint, url.PathEscape(username))
+	resp, err := c.conditionalGetRequest(ctx, path, c.rateLimiter, vals)
 	if err != nil {
 		if os.IsTimeout(err) {
 			return nil, ghsearch.ErrUserSourceTimeout
 		}
 		return nil, err
 	}
-	c.RateLimit.updateFrom(resp.Header)
+	if resp.StatusCode == http.StatusNotModified && prev != nil {
+		// Not modified responses are free, give back what we consumed
+		// before reconciling with the server.
+		c.rateLimiter.Release(ctx)
+	}
+	c.rateLimiter.updateFrom(ctx, resp.Header)
+	traceResponse(span, resp.StatusCode, c.rateLimiter.RateLimit())
+	if responseRateLimited(resp) {
+		return nil, ghsearch.NewRateLimitError(ErrRateLimitHit, rateLimitFrom(resp.Header).ResetsAt)
+	}
+	if resp.StatusCode == http.StatusNotModified && prev != nil {
+		resp.Body.Close()
+		return prev.User, nil
+	}
 	if resp.StatusCode == http.StatusNotFound {
 		return nil, ghsearch.ErrUserNotFound
 	}
+	if err = c.unauthorizedError(resp); err != nil {
+		return nil, err
+	}
 	if responseHasError(resp) {
 		return nil, ghsearch.ErrUserSourceFailed
 	}
@@ -47,5 +98,42 @@ func (c *Client) fetchUser(ctx context.Context, username string) (*ghsearch.User
 	if err = decodeBody(resp, &u); err != nil {
 		return nil, err
 	}
+	c.cacheUser(ctx, username, validatorsFrom(resp.Header), &u)
 	return &u, err
 }
+
+// conditionalUser represents a user response with its validators.
+type conditionalUser struct {
+	validators
+	User *ghsearch.User `json:"user"`
+}
+
+// cachedUser returns previous user response, cache failures are
+// treated as miss since it will only cost a full request.
+func (c *Client) cachedUser(ctx context.Context, username string) *conditionalUser {
+	if c.validatorCache == nil {
+		return nil
+	}
+
+	var cu conditionalUser
+	hit, err := c.validatorCache.Get(ctx, c.validatorKey(username), &cu)
+	if err != nil || !hit || cu.User == nil || cu.empty() {
+		return nil
+	}
+	return &cu
+}
+
+func (c *Client) cacheUser(ctx context.Context, username string, v validators, u *ghsearch.User) {
+	if c.validatorCache == nil || v.empty() {
+		return
+	}
+
+	cu := conditionalUser{v, u}
+	_ = c.validatorCache.Set(ctx, c.validatorKey(username), cu, validatorCacheExpr)
+}
+
+// validatorKey identifies user validators of the access token without
+// exposing the token itself.
+func (c *Client) validatorKey(username string) string {
+	return validatorCacheKeyPrefix + tokenHash(c.accessToken) + ":" + username
+}
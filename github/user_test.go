@@ -2,11 +2,15 @@ package github_test
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
 	"strconv"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -82,24 +86,134 @@ func TestClient_User(t *testing.T) {
 	for _, tc := range testcases {
 		ctx := context.Background()
 		gcl := github.NewCustomClient(tc.testSrv.URL, "", tc.timeout)
-		gcl.RateLimit = github.RateLimit{
+		gcl.SetRateLimit(github.RateLimit{
 			Limit:     60,
 			Remaining: 0,
 			Used:      0,
 			ResetsAt:  time.Now(),
-		}
+		})
 		got, gotErr := gcl.User(ctx, tc.username)
 		if !reflect.DeepEqual(got, tc.want) {
 			t.Errorf("\ngot: \n\t%#v \nwant: \n\t%#v", got, tc.want)
 		}
-		if gotErr != tc.wantErr {
+		if !errors.Is(gotErr, tc.wantErr) {
 			t.Errorf("err: %#v, want: %#v", gotErr, tc.wantErr)
 		}
 	}
 }
 
+func TestClient_User_Conditional(t *testing.T) {
+	const etag = `"b3b4a1"`
+	var conditionalHits int
+	testSrv := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+		setDefaultTestHeaders(w.Header())
+		if r.Header.Get(github.HeaderIfNoneMatch) == etag {
+			conditionalHits++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set(github.HeaderETag, etag)
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, rawRespBodyUser)
+	})
+
+	ctx := context.Background()
+	gcl := github.NewCustomClient(testSrv.URL, "", 0)
+	gcl.SetRateLimit(github.RateLimit{Limit: 60, Remaining: 60})
+	gcl.SetValidatorCache(&mockedCache{})
+
+	want, err := gcl.User(ctx, "kudarap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := gcl.User(ctx, "kudarap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("\ngot: \n\t%#v \nwant: \n\t%#v", got, want)
+	}
+	if conditionalHits != 1 {
+		t.Errorf("conditional hits: %d, want: 1", conditionalHits)
+	}
+	if gcl.RateLimit().Remaining != 59 {
+		t.Errorf("rate limit remaining: %d, want: 59", gcl.RateLimit().Remaining)
+	}
+}
+
+func TestClient_User_ConditionalPerToken(t *testing.T) {
+	const etag = `"b3b4a1"`
+	var conditionalHits int
+	testSrv := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+		setDefaultTestHeaders(w.Header())
+		if r.Header.Get(github.HeaderIfNoneMatch) == etag {
+			conditionalHits++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set(github.HeaderETag, etag)
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, rawRespBodyUser)
+	})
+
+	// Validators of one token are not replayed by another sharing the cache.
+	ctx := context.Background()
+	cache := &mockedCache{}
+	for _, token := range []string{"service", "caller"} {
+		gcl := github.NewCustomClient(testSrv.URL, token, 0)
+		gcl.SetRateLimit(github.RateLimit{Limit: 60, Remaining: 60})
+		gcl.SetValidatorCache(cache)
+		if _, err := gcl.User(ctx, "kudarap"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if conditionalHits != 0 {
+		t.Errorf("conditional hits: %d, want: 0", conditionalHits)
+	}
+	if len(cache.data) != 2 {
+		t.Errorf("cached validators: %d, want: 2", len(cache.data))
+	}
+}
+
 func TestClient_User_SingleFlight(t *testing.T) {
+	const callers = 3
+	var requests int32
+	release := make(chan struct{})
+	testSrv := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		<-release
+		setDefaultTestHeaders(w.Header())
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, rawRespBodyUser)
+	})
+	defer testSrv.Close()
+
+	obs := &mockedObserver{requests: map[string]int{}, deduped: map[string]int{}}
+	gcl := github.NewCustomClient(testSrv.URL, "", 0)
+	gcl.SetRateLimit(github.RateLimit{Limit: 60, Remaining: 60})
+	gcl.SetObserver(obs)
+
+	var wg sync.WaitGroup
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := gcl.User(context.Background(), "kudarap"); err != nil {
+				t.Errorf("err: %#v, want: nil", err)
+			}
+		}()
+	}
+	// Lets duplicate callers join the in-flight request.
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
 
+	if got := atomic.LoadInt32(&requests); got != 1 {
+		t.Errorf("requests: %d, want: %d", got, 1)
+	}
+	if got := obs.deduped["github"]; got != callers-1 {
+		t.Errorf("deduped: %d, want: %d", got, callers-1)
+	}
 }
 
 func setDefaultTestHeaders(h http.Header) {
@@ -157,6 +271,68 @@ const rawRespBody500 = `{
   "message": "Internal Error"
 }`
 
+type mockedCache struct {
+	data map[string][]byte
+}
+
+func (mc *mockedCache) Get(ctx context.Context, key string, out interface{}) (bool, error) {
+	b, ok := mc.data[key]
+	if !ok {
+		return false, nil
+	}
+	return true, json.Unmarshal(b, out)
+}
+
+func (mc *mockedCache) Set(ctx context.Context, key string, val interface{}, expr time.Duration) error {
+	if mc.data == nil {
+		mc.data = map[string][]byte{}
+	}
+	b, err := json.Marshal(val)
+	if err != nil {
+		return err
+	}
+	mc.data[key] = b
+	return nil
+}
+
+func TestClient_User_RequestID(t *testing.T) {
+	var got string
+	testSrv := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get(github.HeaderRequestID)
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, rawRespBodyUser)
+	})
+	defer testSrv.Close()
+
+	gcl := github.NewCustomClient(testSrv.URL, "", 0)
+	gcl.SetRateLimit(github.RateLimit{Limit: 60, Remaining: 60})
+	ctx := ghsearch.WithRequestID(context.Background(), "req-1")
+	if _, err := gcl.User(ctx, "kudarap"); err != nil {
+		t.Fatalf("err: %#v, want: nil", err)
+	}
+	if got != "req-1" {
+		t.Errorf("request id: %s, want: %s", got, "req-1")
+	}
+}
+
+func TestClient_User_PathEscape(t *testing.T) {
+	var got string
+	testSrv := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+		got = r.URL.EscapedPath() + "?" + r.URL.RawQuery
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprintf(w, rawRespBody404)
+	})
+	defer testSrv.Close()
+
+	gcl := github.NewCustomClient(testSrv.URL, "", 0)
+	gcl.SetRateLimit(github.RateLimit{Limit: 60, Remaining: 60})
+	gcl.User(context.Background(), "a/b?x=1")
+	want := "/users/a%2Fb%3Fx=1?"
+	if got != want {
+		t.Errorf("path: %s, want: %s", got, want)
+	}
+}
+
 func newTestServer(fn func(w http.ResponseWriter, r *http.Request)) *httptest.Server {
 	return httptest.NewServer(http.HandlerFunc(fn))
 }
@@ -0,0 +1,151 @@
+package http
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/kudarap/ghsearch"
+)
+
+// CacheAdmin provides cached users inspection and invalidation.
+type CacheAdmin interface {
+	CachedUser(ctx context.Context, username string) (*ghsearch.CachedUser, error)
+	DeleteUser(ctx context.Context, username string) error
+	DeleteKeys(ctx context.Context, pattern string) (int, error)
+	RefreshUser(ctx context.Context, username string) (*ghsearch.User, error)
+}
+
+// GETCachedUser handles cached user inspection requests.
+func (h *RestHandler) GETCachedUser() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		username := mux.Vars(r)["username"]
+		if err := ghsearch.ValidateUsername(username); err != nil {
+			encodeJSONError(w, r, err)
+			return
+		}
+
+		cached, err := h.cacheAdmin.CachedUser(r.Context(), username)
+		if err != nil {
+			encodeJSONError(w, r, err)
+			return
+		}
+		encodeJSONResp(w, newCachedUserResp(cached), http.StatusOK)
+	}
+}
+
+// DELETECachedUser handles cached user invalidation requests.
+func (h *RestHandler) DELETECachedUser() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		username := mux.Vars(r)["username"]
+		if err := ghsearch.ValidateUsername(username); err != nil {
+			encodeJSONError(w, r, err)
+			return
+		}
+
+		if err := h.cacheAdmin.DeleteUser(r.Context(), username); err != nil {
+			encodeJSONError(w, r, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// DELETECacheKeys handles cached users invalidation by username pattern requests.
+func (h *RestHandler) DELETECacheKeys() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		pattern := strings.TrimSpace(r.URL.Query().Get("pattern"))
+		if pattern == "" {
+			encodeJSONError(w, r, fmt.Errorf("%w: pattern required", errInvalidInput))
+			return
+		}
+
+		n, err := h.cacheAdmin.DeleteKeys(r.Context(), pattern)
+		if err != nil {
+			encodeJSONError(w, r, err)
+			return
+		}
+		encodeJSONResp(w, deleteKeysResp{Pattern: pattern, Deleted: n}, http.StatusOK)
+	}
+}
+
+// POSTRefreshCachedUser handles forced cached user refresh requests.
+func (h *RestHandler) POSTRefreshCachedUser() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		username := mux.Vars(r)["username"]
+		if err := ghsearch.ValidateUsername(username); err != nil {
+			encodeJSONError(w, r, err)
+			return
+		}
+
+		user, err := h.cacheAdmin.RefreshUser(r.Context(), username)
+		if err != nil {
+			encodeJSONError(w, r, err)
+			return
+		}
+		encodeJSONResp(w, user, http.StatusOK)
+	}
+}
+
+// SetAdminToken enables admin endpoints authenticated with bearer token.
+func (h *RestHandler) SetAdminToken(token string) {
+	h.adminToken = token
+}
+
+// SetCacheAdmin enables cache admin endpoints.
+func (h *RestHandler) SetCacheAdmin(ca CacheAdmin) {
+	h.cacheAdmin = ca
+}
+
+// adminAuth rejects requests without the admin bearer token.
+func (h *RestHandler) adminAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := bearerToken(r)
+		if !ok || h.adminToken == "" || subtle.ConstantTimeCompare([]byte(token), []byte(h.adminToken)) != 1 {
+			encodeJSONError(w, r, errUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// bearerToken returns token of bearer authorization header, ok is false
+// when the header is missing or uses another scheme.
+func bearerToken(r *http.Request) (token string, ok bool) {
+	const prefix = "Bearer "
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, prefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(auth, prefix), true
+}
+
+// cachedUserResp represents cached user inspection response.
+type cachedUserResp struct {
+	Username   string         `json:"username"`
+	User       *ghsearch.User `json:"user,omitempty"`
+	NotFound   bool           `json:"not_found,omitempty"`
+	CachedAt   time.Time      `json:"cached_at"`
+	AgeSeconds int            `json:"age_seconds"`
+	TTLSeconds int            `json:"ttl_seconds"`
+}
+
+func newCachedUserResp(c *ghsearch.CachedUser) cachedUserResp {
+	return cachedUserResp{
+		Username:   c.Username,
+		User:       c.User,
+		NotFound:   c.NotFound,
+		CachedAt:   c.CachedAt,
+		AgeSeconds: int(time.Since(c.CachedAt).Seconds()),
+		TTLSeconds: int(c.TTL.Seconds()),
+	}
+}
+
+type deleteKeysResp struct {
+	Pattern string `json:"pattern"`
+	Deleted int    `json:"deleted"`
+}
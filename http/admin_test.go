@@ -0,0 +1,87 @@
+package http
+
+import (
+	"context"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kudarap/ghsearch"
+)
+
+func TestAdminEndpoints(t *testing.T) {
+	const token = "secret"
+	const bearer = "Bearer " + token
+	testcases := []struct {
+		name string
+		// args
+		method string
+		path   string
+		auth   string
+		// returns
+		wantStatus int
+	}{
+		{"no token", http.MethodGet, "/admin/cache/users/kudarap", "", http.StatusUnauthorized},
+		{"wrong token", http.MethodGet, "/admin/cache/users/kudarap", "Bearer guess", http.StatusUnauthorized},
+		{"token without scheme", http.MethodGet, "/admin/cache/users/kudarap", token, http.StatusUnauthorized},
+		{"inspect", http.MethodGet, "/admin/cache/users/kudarap", bearer, http.StatusOK},
+		{"inspect miss", http.MethodGet, "/admin/cache/users/dazz", bearer, http.StatusNotFound},
+		{"delete", http.MethodDelete, "/admin/cache/users/kudarap", bearer, http.StatusNoContent},
+		{"inspect invalid", http.MethodGet, "/admin/cache/users/kud*", bearer, http.StatusBadRequest},
+		{"delete miss", http.MethodDelete, "/admin/cache/users/dazz", bearer, http.StatusNotFound},
+		{"delete invalid", http.MethodDelete, "/admin/cache/users/kud*", bearer, http.StatusBadRequest},
+		{"refresh", http.MethodPost, "/admin/cache/users/kudarap/refresh", bearer, http.StatusOK},
+		{"refresh invalid", http.MethodPost, "/admin/cache/users/-bad-/refresh", bearer, http.StatusBadRequest},
+		{"delete keys", http.MethodDelete, "/admin/cache/keys?pattern=kud*", bearer, http.StatusOK},
+		{"delete keys no pattern", http.MethodDelete, "/admin/cache/keys", bearer, http.StatusBadRequest},
+	}
+
+	rest := NewRestHandler(nil)
+	rest.SetAdminToken(token)
+	rest.SetCacheAdmin(&mockedCacheAdmin{users: map[string]*ghsearch.User{
+		"kudarap": {Login: "kudarap"},
+	}})
+	srv := NewServer("", rest, log.New(ioutil.Discard, "", 0))
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			if tc.auth != "" {
+				req.Header.Set("Authorization", tc.auth)
+			}
+			w := httptest.NewRecorder()
+			srv.srv.Handler.ServeHTTP(w, req)
+			if w.Code != tc.wantStatus {
+				t.Errorf("status: %d, want: %d", w.Code, tc.wantStatus)
+			}
+		})
+	}
+}
+
+type mockedCacheAdmin struct {
+	users map[string]*ghsearch.User
+}
+
+func (m *mockedCacheAdmin) CachedUser(ctx context.Context, username string) (*ghsearch.CachedUser, error) {
+	u, ok := m.users[username]
+	if !ok {
+		return nil, ghsearch.ErrCacheMiss
+	}
+	return &ghsearch.CachedUser{Username: username, User: u}, nil
+}
+
+func (m *mockedCacheAdmin) DeleteUser(ctx context.Context, username string) error {
+	if _, ok := m.users[username]; !ok {
+		return ghsearch.ErrCacheMiss
+	}
+	return nil
+}
+
+func (m *mockedCacheAdmin) DeleteKeys(ctx context.Context, pattern string) (int, error) {
+	return len(m.users), nil
+}
+
+func (m *mockedCacheAdmin) RefreshUser(ctx context.Context, username string) (*ghsearch.User, error) {
+	return &ghsearch.User{Login: username}, nil
+}
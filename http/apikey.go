@@ -0,0 +1,242 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	chimw "github.com/go-chi/chi/v5/middleware"
+	"github.com/kudarap/ghsearch"
+	"github.com/sirupsen/logrus"
+)
+
+// HeaderAPIKey is a request header key of API key.
+const HeaderAPIKey = "X-API-Key"
+
+// API key scopes grant access to endpoint groups.
+const (
+	// ScopeAll grants access to all endpoints.
+	ScopeAll = "*"
+	// ScopeUsers grants access to user lookups.
+	ScopeUsers = "users"
+	// ScopeStatus grants access to service status.
+	ScopeStatus = "status"
+)
+
+// routeScopes maps route templates to its scope, unlisted routes requires ScopeAll.
+var routeScopes = map[string]string{
+	"/users":                  ScopeUsers,
+	"/users/search":           ScopeUsers,
+	"/status/circuit-breaker": ScopeStatus,
+}
+
+// errForbidden indicates API key is not allowed to access the endpoint.
+var errForbidden = errors.New("api key is not allowed to access this endpoint")
+
+// SetAPIKeys requires API key on every endpoint except probes, metrics and
+// admin endpoints that has its own token.
+func (h *RestHandler) SetAPIKeys(s ghsearch.APIKeyStore) {
+	h.apiKeys = s
+	h.apiKeyUsage = &apiKeyUsage{keys: map[string]*apiKeyUsageResp{}}
+}
+
+// GETAPIKeyUsage handles API key usage requests.
+func (h *RestHandler) GETAPIKeyUsage() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		encodeJSONResp(w, h.apiKeyUsage.list(), http.StatusOK)
+	}
+}
+
+// apiKeyAuth rejects requests without a known API key or out of its scopes,
+// the key identity is attached to the request logs, metrics and usage.
+func (h *RestHandler) apiKeyAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if unmeteredPaths[r.URL.Path] || strings.HasPrefix(r.URL.Path, "/admin/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		key := r.Header.Get(HeaderAPIKey)
+		if key == "" {
+			encodeJSONError(w, r, errUnauthorized)
+			return
+		}
+		k, err := h.apiKeys.APIKey(r.Context(), key)
+		if errors.Is(err, ghsearch.ErrAPIKeyNotFound) {
+			err = errUnauthorized
+		}
+		if err != nil {
+			encodeJSONError(w, r, err)
+			return
+		}
+		if e, ok := chimw.GetLogEntry(r).(*apiKeyLogEntry); ok {
+			e.apiKeyID = k.ID
+		}
+
+		route := routeTemplate(r)
+		ww := chimw.NewWrapResponseWriter(w, r.ProtoMajor)
+		if hasScope(k, routeScopes[route]) {
+			next.ServeHTTP(ww, r.WithContext(withAPIKey(r.Context(), k)))
+		} else {
+			encodeJSONError(ww, r, errForbidden)
+		}
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		h.apiKeyUsage.record(k.ID, status)
+		if h.metrics != nil {
+			h.metrics.APIKeyRequest(k.ID, route, status)
+		}
+	})
+}
+
+func hasScope(k *ghsearch.APIKey, scope string) bool {
+	for _, s := range k.Scopes {
+		if s == ScopeAll || (scope != "" && s == scope) {
+			return true
+		}
+	}
+	return false
+}
+
+type apiKeyCtxKey struct{}
+
+// withAPIKey sets authenticated API key used for its rate limit
+// instead of client address.
+func withAPIKey(ctx context.Context, k *ghsearch.APIKey) context.Context {
+	return context.WithValue(ctx, apiKeyCtxKey{}, k)
+}
+
+func apiKeyFrom(ctx context.Context) *ghsearch.APIKey {
+	k, _ := ctx.Value(apiKeyCtxKey{}).(*ghsearch.APIKey)
+	return k
+}
+
+// apiKeyRateLimit returns API key own rate limit, burst defaults to a second worth.
+func apiKeyRateLimit(k *ghsearch.APIKey) (ClientRateLimit, bool) {
+	if k.Rate <= 0 {
+		return ClientRateLimit{}, false
+	}
+	burst := k.Burst
+	if burst <= 0 {
+		burst = int(math.Ceil(k.Rate))
+	}
+	return ClientRateLimit{Rate: k.Rate, Burst: burst}, true
+}
+
+// apiKeyUsage counts requests per API key since the instance started.
+type apiKeyUsage struct {
+	mu   sync.Mutex
+	keys map[string]*apiKeyUsageResp
+}
+
+func (u *apiKeyUsage) record(id string, status int) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	ku, ok := u.keys[id]
+	if !ok {
+		ku = &apiKeyUsageResp{Key: id}
+		u.keys[id] = ku
+	}
+	ku.Requests++
+	if status == http.StatusForbidden || status == http.StatusTooManyRequests {
+		ku.Rejected++
+	}
+	ku.LastUsedAt = time.Now()
+}
+
+func (u *apiKeyUsage) list() []apiKeyUsageResp {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	l := make([]apiKeyUsageResp, 0, len(u.keys))
+	for _, ku := range u.keys {
+		l = append(l, *ku)
+	}
+	sort.Slice(l, func(i, j int) bool { return l[i].Key < l[j].Key })
+	return l
+}
+
+// apiKeyUsageResp represents API key usage response item.
+type apiKeyUsageResp struct {
+	Key        string    `json:"key"`
+	Requests   int       `json:"requests"`
+	Rejected   int       `json:"rejected"`
+	LastUsedAt time.Time `json:"last_used_at"`
+}
+
+// apiKeyLogFormatter adds API key identity as a field of request logs.
+type apiKeyLogFormatter struct {
+	*chimw.DefaultLogFormatter
+}
+
+func (f *apiKeyLogFormatter) NewLogEntry(r *http.Request) chimw.LogEntry {
+	return &apiKeyLogEntry{formatter: f.DefaultLogFormatter, r: r}
+}
+
+// apiKeyLogEntry defers formatting until written since the
+// API key is only known once authenticated.
+type apiKeyLogEntry struct {
+	formatter *chimw.DefaultLogFormatter
+	r         *http.Request
+	apiKeyID  string
+}
+
+func (e *apiKeyLogEntry) Write(status, bytes int, header http.Header, elapsed time.Duration, extra interface{}) {
+	l := withLogContext(e.formatter.Logger, e.r.Context())
+	if e.apiKeyID != "" {
+		l = withLogField(l, "api_key", e.apiKeyID)
+	}
+	f := &chimw.DefaultLogFormatter{Logger: l, NoColor: e.formatter.NoColor}
+	f.NewLogEntry(e.r).Write(status, bytes, header, elapsed, extra)
+}
+
+func (e *apiKeyLogEntry) Panic(v interface{}, stack []byte) {
+	chimw.PrintPrettyStack(v)
+}
+
+// fieldLogger logs structured fields, implemented by logrus loggers.
+type fieldLogger interface {
+	WithField(key string, value interface{}) *logrus.Entry
+}
+
+// contextLogger logs with request context, implemented by logrus loggers
+// so hooks can read request scoped values like request id.
+type contextLogger interface {
+	WithContext(ctx context.Context) *logrus.Entry
+}
+
+// withLogContext returns logger that logs with ctx, loggers without
+// context support already get request id prefixed by chi.
+func withLogContext(l chimw.LoggerInterface, ctx context.Context) chimw.LoggerInterface {
+	if cl, ok := l.(contextLogger); ok {
+		return cl.WithContext(ctx)
+	}
+	return l
+}
+
+// withLogField returns logger that logs field along with each entry,
+// loggers without structured fields get it as key=value suffix.
+func withLogField(l chimw.LoggerInterface, key, value string) chimw.LoggerInterface {
+	if fl, ok := l.(fieldLogger); ok {
+		return fl.WithField(key, value)
+	}
+	return &suffixLogger{l, " " + key + "=" + value}
+}
+
+type suffixLogger struct {
+	chimw.LoggerInterface
+	suffix string
+}
+
+func (l *suffixLogger) Print(v ...interface{}) {
+	l.LoggerInterface.Print(append(v, l.suffix)...)
+}
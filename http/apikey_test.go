@@ -0,0 +1,149 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	chimw "github.com/go-chi/chi/v5/middleware"
+	"github.com/kudarap/ghsearch"
+	"github.com/kudarap/ghsearch/logging"
+	"github.com/sirupsen/logrus"
+)
+
+func TestRestHandler_apiKeyAuth(t *testing.T) {
+	testcases := []struct {
+		name string
+		// deps
+		limited bool
+		// args
+		path   string
+		apiKey string
+		// returns
+		wantStatus int
+		wantUsage  []apiKeyUsageResp
+		wantLog    string
+	}{
+		{"missing key", false, "/users?usernames=kudarap", "", http.StatusUnauthorized, []apiKeyUsageResp{}, ""},
+		{"unknown key", false, "/users?usernames=kudarap", "nope", http.StatusUnauthorized, []apiKeyUsageResp{}, ""},
+		{"scoped key", false, "/users?usernames=kudarap", "users-key", http.StatusOK,
+			[]apiKeyUsageResp{{Key: "frontend", Requests: 1}}, "api_key=frontend"},
+		{"out of scope", false, "/status/circuit-breaker", "users-key", http.StatusForbidden,
+			[]apiKeyUsageResp{{Key: "frontend", Requests: 1, Rejected: 1}}, "api_key=frontend"},
+		{"all scopes", false, "/status/circuit-breaker", "ops-key", http.StatusOK,
+			[]apiKeyUsageResp{{Key: "ops", Requests: 1}}, "api_key=ops"},
+		{"limited key", true, "/users?usernames=kudarap", "users-key", http.StatusTooManyRequests,
+			[]apiKeyUsageResp{{Key: "frontend", Requests: 1, Rejected: 1}}, "api_key=frontend"},
+		{"probe", false, "/healthz", "", http.StatusOK, []apiKeyUsageResp{}, ""},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			rest := NewRestHandler(&policyUserService{})
+			rest.SetCircuitBreaker(ghsearch.NewCircuitBreaker(nil, ghsearch.DefaultCircuitBreakerConfig))
+			rest.SetAdminToken("secret")
+			rest.SetAPIKeys(mockedAPIKeyStore{
+				"users-key": {ID: "frontend", Scopes: []string{ScopeUsers}, Rate: 1, Burst: 1},
+				"ops-key":   {ID: "ops", Scopes: []string{ScopeAll}},
+			})
+			rest.SetClientRateLimit(&mockedClientRateLimiter{ok: !tc.limited}, ClientRateLimit{})
+			srv := NewServer("", rest, log.New(&logs, "", 0))
+
+			r := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			if tc.apiKey != "" {
+				r.Header.Set(HeaderAPIKey, tc.apiKey)
+			}
+			w := httptest.NewRecorder()
+			srv.srv.Handler.ServeHTTP(w, r)
+			if w.Code != tc.wantStatus {
+				t.Errorf("status: %d, want: %d", w.Code, tc.wantStatus)
+			}
+
+			// Key identity is a field of its own and leaves the remote address as is.
+			if tc.wantLog != "" && !strings.Contains(logs.String(), tc.wantLog) {
+				t.Errorf("logs should have %s: %s", tc.wantLog, logs.String())
+			}
+			if strings.Contains(logs.String(), r.RemoteAddr+" key=") {
+				t.Errorf("logs should not alter remote address: %s", logs.String())
+			}
+
+			if got := requestAPIKeyUsage(t, srv); !reflect.DeepEqual(got, tc.wantUsage) {
+				t.Errorf("\ngot: \n\t%#v \nwant: \n\t%#v", got, tc.wantUsage)
+			}
+		})
+	}
+}
+
+// requestAPIKeyUsage returns API key usage from admin endpoint without last used time.
+func requestAPIKeyUsage(t *testing.T, srv *Server) []apiKeyUsageResp {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodGet, "/admin/api-keys/usage", nil)
+	r.Header.Set("Authorization", "Bearer secret")
+	w := httptest.NewRecorder()
+	srv.srv.Handler.ServeHTTP(w, r)
+	var got []apiKeyUsageResp
+	if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	for i := range got {
+		if got[i].LastUsedAt.IsZero() {
+			t.Errorf("%s last used at should be set", got[i].Key)
+		}
+		got[i].LastUsedAt = time.Time{}
+	}
+	return got
+}
+
+type mockedAPIKeyStore map[string]ghsearch.APIKey
+
+func (m mockedAPIKeyStore) APIKey(_ context.Context, key string) (*ghsearch.APIKey, error) {
+	k, ok := m[key]
+	if !ok {
+		return nil, ghsearch.ErrAPIKeyNotFound
+	}
+	return &k, nil
+}
+
+func TestWithLogField(t *testing.T) {
+	var out bytes.Buffer
+	l := logrus.New()
+	l.SetOutput(&out)
+	l.SetFormatter(&logrus.JSONFormatter{})
+	withLogField(l, "api_key", "frontend").Print("GET /users")
+
+	var got map[string]interface{}
+	if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got["api_key"] != "frontend" || got["msg"] != "GET /users" {
+		t.Errorf("got: %v, want api_key field and untouched message", got)
+	}
+}
+
+func TestStructuredLogger_RequestID(t *testing.T) {
+	var out bytes.Buffer
+	l := logging.New()
+	l.SetOutput(&out)
+	l.SetFormatter(&logrus.JSONFormatter{})
+	h := requestID(structuredLogger(l)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		chimw.GetLogEntry(r).(*apiKeyLogEntry).apiKeyID = "frontend"
+	})))
+	r := httptest.NewRequest(http.MethodGet, "/users", nil)
+	r.Header.Set(HeaderRequestID, "req-1")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+
+	var got map[string]interface{}
+	if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got["request_id"] != "req-1" || got["api_key"] != "frontend" {
+		t.Errorf("got: %v, want request_id and api_key fields", got)
+	}
+}
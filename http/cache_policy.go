@@ -0,0 +1,90 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kudarap/ghsearch"
+)
+
+const (
+	// queryCache is an equivalent of Cache-Control request header
+	// for clients that cant set headers, it takes precedence.
+	queryCache = "cache"
+
+	// DefaultCacheBypassLimit sets how many cache bypass requests each client is allowed per minute.
+	DefaultCacheBypassLimit = 60
+)
+
+// cachePolicyFrom parses cache policy from request query or Cache-Control
+// header, supports no-cache and max-age directives while ignoring others.
+func cachePolicyFrom(r *http.Request) (ghsearch.CachePolicy, error) {
+	v := r.URL.Query().Get(queryCache)
+	if v == "" {
+		v = r.Header.Get("Cache-Control")
+	}
+
+	var noCache bool
+	var maxAge *int
+	for _, d := range strings.Split(v, ",") {
+		d = strings.ToLower(strings.TrimSpace(d))
+		switch {
+		case d == "no-cache":
+			noCache = true
+		case strings.HasPrefix(d, "max-age="):
+			secs, err := strconv.Atoi(strings.TrimPrefix(d, "max-age="))
+			if err != nil {
+				return ghsearch.CachePolicy{}, fmt.Errorf("%w: max-age must be non-negative seconds", errInvalidInput)
+			}
+			maxAge = &secs
+		}
+	}
+	return newCachePolicy(noCache, maxAge)
+}
+
+// newCachePolicy normalizes cache directives the same on all endpoints, zero
+// max age requires a new user value same as no-cache and nil uses cache default.
+func newCachePolicy(noCache bool, maxAge *int) (ghsearch.CachePolicy, error) {
+	p := ghsearch.CachePolicy{NoCache: noCache}
+	switch {
+	case maxAge == nil:
+	case *maxAge < 0:
+		return p, fmt.Errorf("%w: max-age must be non-negative seconds", errInvalidInput)
+	case *maxAge == 0:
+		p.NoCache = true
+	default:
+		p.MaxAge = time.Duration(*maxAge) * time.Second
+	}
+	return p, nil
+}
+
+// allowCacheBypass checks cache bypass limit of the client, no-cache and max-age
+// shorter than cache freshness are both bypass. Returns rate limit
+// error with the next available time when exceeded. Limiter failure lets
+// requests through same as client rate limit.
+func (h *RestHandler) allowCacheBypass(r *http.Request) error {
+	if h.bypassLimiter == nil {
+		return nil
+	}
+	if h.bypassLimit <= 0 {
+		return ghsearch.NewRateLimitError(errCacheBypassLimited, time.Time{})
+	}
+
+	rate := float64(h.bypassLimit) / time.Minute.Seconds()
+	ok, _, err := h.bypassLimiter.TakeToken(r.Context(), "bypass:"+h.clientKey(r), rate, h.bypassLimit)
+	if err != nil || ok {
+		return nil
+	}
+	return ghsearch.NewRateLimitError(errCacheBypassLimited, time.Now().Add(refillDuration(1, rate)))
+}
+
+// SetCacheBypassLimit enables cache bypass limit of how many requests each
+// client is allowed per minute to protect source rate limit, zero rejects
+// all of them.
+func (h *RestHandler) SetCacheBypassLimit(l ClientRateLimiter, perMinute int) {
+	h.bypassLimiter = l
+	h.bypassLimit = perMinute
+}
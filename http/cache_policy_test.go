@@ -0,0 +1,133 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/kudarap/ghsearch"
+	"github.com/kudarap/ghsearch/memory"
+)
+
+func TestCachePolicyFrom(t *testing.T) {
+	testcases := []struct {
+		name string
+		// args
+		query  string
+		header string
+		// returns
+		want    ghsearch.CachePolicy
+		wantErr error
+	}{
+		{"none", "", "", ghsearch.CachePolicy{}, nil},
+		{"no-cache header", "", "no-cache", ghsearch.CachePolicy{NoCache: true}, nil},
+		{"max-age header", "", "public, Max-Age=120", ghsearch.CachePolicy{MaxAge: 2 * time.Minute}, nil},
+		{"zero max-age", "", "max-age=0", ghsearch.CachePolicy{NoCache: true}, nil},
+		{"short max-age", "", "max-age=1", ghsearch.CachePolicy{MaxAge: time.Second}, nil},
+		{"query precedence", "max-age=60", "no-cache", ghsearch.CachePolicy{MaxAge: time.Minute}, nil},
+		{"invalid max-age", "max-age=soon", "", ghsearch.CachePolicy{}, errInvalidInput},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/users?cache="+tc.query, nil)
+			if tc.header != "" {
+				r.Header.Set("Cache-Control", tc.header)
+			}
+			got, gotErr := cachePolicyFrom(r)
+			if !errors.Is(gotErr, tc.wantErr) {
+				t.Fatalf("err: %#v, want: %#v", gotErr, tc.wantErr)
+			}
+			if gotErr == nil && !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("\ngot: \n\t%#v \nwant: \n\t%#v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestCachePolicy_SameOnAllEndpoints(t *testing.T) {
+	testcases := []struct {
+		name string
+		// args
+		header string
+		body   string
+	}{
+		{"default", "", `{}`},
+		{"no-cache", "no-cache", `{"no_cache": true}`},
+		{"zero max-age", "max-age=0", `{"max_age": 0}`},
+		{"max-age", "max-age=300", `{"max_age": 300}`},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/users", nil)
+			r.Header.Set("Cache-Control", tc.header)
+			want, err := cachePolicyFrom(r)
+			if err != nil {
+				t.Fatalf("err: %#v, want: nil", err)
+			}
+
+			req := usersSearchReq{Cache: &cachePolicyReq{}}
+			if err = json.Unmarshal([]byte(tc.body), req.Cache); err != nil {
+				t.Fatal(err)
+			}
+			got, err := req.cachePolicy(httptest.NewRequest(http.MethodPost, "/users/search", nil))
+			if err != nil {
+				t.Fatalf("err: %#v, want: nil", err)
+			}
+			if got != want {
+				t.Errorf("\ngot: \n\t%#v \nwant: \n\t%#v", got, want)
+			}
+		})
+	}
+}
+
+func TestRestHandler_GETUsers_CacheBypassLimit(t *testing.T) {
+	svc := &policyUserService{}
+	h := NewRestHandler(svc)
+	h.SetCacheBypassLimit(memory.NewRateLimiter(10), 1)
+
+	testcases := []struct {
+		name string
+		// args
+		remoteAddr   string
+		cacheControl string
+		// returns
+		wantStatus int
+	}{
+		{"bypass", "10.0.0.1:1234", "no-cache", http.StatusOK},
+		{"bypass limited", "10.0.0.1:1234", "no-cache", http.StatusTooManyRequests},
+		{"short max-age limited", "10.0.0.1:1234", "max-age=10", http.StatusTooManyRequests},
+		{"cached read not limited", "10.0.0.1:1234", "", http.StatusOK},
+		{"fresh max-age not limited", "10.0.0.1:1234", "max-age=120", http.StatusOK},
+		{"other client not limited", "10.0.0.2:1234", "max-age=10", http.StatusOK},
+	}
+	for _, tc := range testcases {
+		r := httptest.NewRequest(http.MethodGet, "/users?usernames=kudarap", nil)
+		r.RemoteAddr = tc.remoteAddr
+		if tc.cacheControl != "" {
+			r.Header.Set("Cache-Control", tc.cacheControl)
+		}
+		w := httptest.NewRecorder()
+		h.GETUsers().ServeHTTP(w, r)
+		if w.Code != tc.wantStatus {
+			t.Errorf("%s status: %d, want: %d", tc.name, w.Code, tc.wantStatus)
+		}
+	}
+}
+
+type policyUserService struct {
+	policy ghsearch.CachePolicy
+}
+
+func (s *policyUserService) Users(ctx context.Context, usernames []string) ([]ghsearch.UserResult, error) {
+	s.policy = ghsearch.CachePolicyFrom(ctx)
+	results := make([]ghsearch.UserResult, len(usernames))
+	for i, u := range usernames {
+		results[i] = ghsearch.UserResult{Username: u, User: &ghsearch.User{Login: u}}
+	}
+	return results, nil
+}
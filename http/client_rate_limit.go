@@ -0,0 +1,186 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kudarap/ghsearch"
+)
+
+// Client rate limit response headers.
+const (
+	HeaderRateLimitLimit     = "X-RateLimit-Limit"
+	HeaderRateLimitRemaining = "X-RateLimit-Remaining"
+	HeaderRateLimitReset     = "X-RateLimit-Reset"
+)
+
+// errClientRateLimited indicates client sent too many requests.
+var errClientRateLimited = errors.New("client rate limit reached")
+
+// unmeteredPaths are used by probes, scrapers and API tooling that
+// should never be throttled nor require API key.
+var unmeteredPaths = map[string]bool{
+	"/healthz":      true,
+	"/readyz":       true,
+	"/metrics":      true,
+	"/openapi.json": true,
+}
+
+// ClientRateLimiter provides token bucket per client key, buckets hold up
+// to burst tokens and refill at rate tokens per second.
+type ClientRateLimiter interface {
+	TakeToken(ctx context.Context, key string, rate float64, burst int) (ok bool, remaining int, err error)
+}
+
+// ClientRateLimit represents token bucket parameters of each client.
+type ClientRateLimit struct {
+	// Rate of requests per second a client can sustain.
+	Rate float64
+	// Burst of requests a client can send at once.
+	Burst int
+}
+
+// SetClientRateLimit enables rate limit per client. Anonymous clients are not
+// limited when rate or burst is zero, API keys with own rate limit still are.
+func (h *RestHandler) SetClientRateLimit(l ClientRateLimiter, conf ClientRateLimit) {
+	h.clientLimiter = l
+	h.clientRateLimit = conf
+}
+
+// SetTrustedProxies sets proxies allowed to forward client address using
+// X-Forwarded-For or X-Real-IP headers, accepts CIDRs or plain IPs.
+func (h *RestHandler) SetTrustedProxies(proxies []string) error {
+	nets := make([]*net.IPNet, len(proxies))
+	for i, p := range proxies {
+		if !strings.Contains(p, "/") {
+			ip := net.ParseIP(p)
+			if ip == nil {
+				return fmt.Errorf("invalid proxy address: %s", p)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			nets[i] = &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}
+			continue
+		}
+
+		_, n, err := net.ParseCIDR(p)
+		if err != nil {
+			return fmt.Errorf("invalid proxy address: %s", p)
+		}
+		nets[i] = n
+	}
+	h.trustedProxies = nets
+	return nil
+}
+
+// rateLimit rejects requests once client bucket is empty. Limiter failure
+// lets requests through since its better to serve than to be down with it.
+func (h *RestHandler) rateLimit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if unmeteredPaths[r.URL.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		conf := h.clientRateLimit
+		if k := apiKeyFrom(r.Context()); k != nil {
+			if kconf, ok := apiKeyRateLimit(k); ok {
+				conf = kconf
+			}
+		}
+		if conf.Rate <= 0 || conf.Burst <= 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ok, remaining, err := h.clientLimiter.TakeToken(r.Context(), h.clientKey(r), conf.Rate, conf.Burst)
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// Reset is when the bucket is full again.
+		now := time.Now()
+		full := now.Add(refillDuration(float64(conf.Burst-remaining), conf.Rate))
+		w.Header().Set(HeaderRateLimitLimit, strconv.Itoa(conf.Burst))
+		w.Header().Set(HeaderRateLimitRemaining, strconv.Itoa(remaining))
+		reset := int64(math.Ceil(float64(full.UnixNano()) / float64(time.Second)))
+		w.Header().Set(HeaderRateLimitReset, strconv.FormatInt(reset, 10))
+		if !ok {
+			retryAt := now.Add(refillDuration(1, conf.Rate))
+			encodeJSONError(w, r, ghsearch.NewRateLimitError(errClientRateLimited, retryAt))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func refillDuration(tokens, rate float64) time.Duration {
+	return time.Duration(tokens / rate * float64(time.Second))
+}
+
+// clientKey identifies client by authenticated API key, or its address
+// when its anonymous. Unauthenticated API key headers are not trusted
+// since clients could rotate them to get fresh buckets.
+func (h *RestHandler) clientKey(r *http.Request) string {
+	if k := apiKeyFrom(r.Context()); k != nil {
+		return "key:" + k.ID
+	}
+	return "ip:" + h.clientIP(r)
+}
+
+// clientIP returns remote address, or the forwarded client address when the
+// request came from trusted proxies. X-Forwarded-For is read from right to
+// left skipping trusted proxies since the leftmost entries can be spoofed.
+func (h *RestHandler) clientIP(r *http.Request) string {
+	remote := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(remote); err == nil {
+		remote = host
+	}
+	ip := net.ParseIP(remote)
+	if ip == nil || !h.trustedProxy(ip) {
+		return remote
+	}
+
+	var hops []string
+	for _, v := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(v, ",")...)
+	}
+	if len(hops) == 0 {
+		if realIP := net.ParseIP(strings.TrimSpace(r.Header.Get("X-Real-IP"))); realIP != nil {
+			return realIP.String()
+		}
+		return remote
+	}
+
+	client := ip
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := net.ParseIP(strings.TrimSpace(hops[i]))
+		if hop == nil {
+			break
+		}
+		client = hop
+		if !h.trustedProxy(hop) {
+			break
+		}
+	}
+	return client.String()
+}
+
+func (h *RestHandler) trustedProxy(ip net.IP) bool {
+	for _, n := range h.trustedProxies {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,124 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/kudarap/ghsearch"
+)
+
+func TestRestHandler_clientIP(t *testing.T) {
+	testcases := []struct {
+		name string
+		// args
+		remoteAddr string
+		forwarded  string
+		realIP     string
+		// returns
+		want string
+	}{
+		{"direct", "203.0.113.7:5000", "", "", "203.0.113.7"},
+		{"untrusted forwarded", "203.0.113.7:5000", "198.51.100.1", "", "203.0.113.7"},
+		{"trusted forwarded", "10.0.0.2:5000", "198.51.100.1", "", "198.51.100.1"},
+		{"spoofed forwarded", "10.0.0.2:5000", "192.0.2.9, 198.51.100.1, 10.0.0.3", "", "198.51.100.1"},
+		{"all trusted forwarded", "10.0.0.2:5000", "10.0.0.4, 10.0.0.3", "", "10.0.0.4"},
+		{"invalid forwarded", "10.0.0.2:5000", "unknown", "", "10.0.0.2"},
+		{"trusted real ip", "10.0.0.2:5000", "", "198.51.100.1", "198.51.100.1"},
+		{"trusted ip proxy", "192.0.2.1:5000", "198.51.100.1", "", "198.51.100.1"},
+	}
+	h := NewRestHandler(nil)
+	if err := h.SetTrustedProxies([]string{"10.0.0.0/8", "192.0.2.1"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/users", nil)
+			r.RemoteAddr = tc.remoteAddr
+			if tc.forwarded != "" {
+				r.Header.Set("X-Forwarded-For", tc.forwarded)
+			}
+			if tc.realIP != "" {
+				r.Header.Set("X-Real-IP", tc.realIP)
+			}
+			if got := h.clientIP(r); got != tc.want {
+				t.Errorf("\ngot: \n\t%#v \nwant: \n\t%#v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRestHandler_SetTrustedProxies_Invalid(t *testing.T) {
+	h := NewRestHandler(nil)
+	if err := h.SetTrustedProxies([]string{"10.0.0.0/33"}); err == nil {
+		t.Error("invalid proxy should fail")
+	}
+}
+
+func TestRestHandler_rateLimit(t *testing.T) {
+	testcases := []struct {
+		name string
+		// deps
+		limiter *mockedClientRateLimiter
+		// args
+		path     string
+		clientID string
+		// returns
+		wantStatus    int
+		wantRemaining string
+		wantKey       string
+	}{
+		{"allowed", &mockedClientRateLimiter{ok: true, remaining: 4}, "/users", "", http.StatusOK, "4", "ip:192.0.2.1"},
+		{"limited", &mockedClientRateLimiter{remaining: 0}, "/users", "", http.StatusTooManyRequests, "0", "ip:192.0.2.1"},
+		{"api key", &mockedClientRateLimiter{ok: true, remaining: 4}, "/users", "ci", http.StatusOK, "4", "key:ci"},
+		{"limiter failure", &mockedClientRateLimiter{err: errors.New("redis down")}, "/users", "", http.StatusOK, "", "ip:192.0.2.1"},
+		{"exempt", &mockedClientRateLimiter{}, "/healthz", "", http.StatusOK, "", ""},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := NewRestHandler(nil)
+			h.SetClientRateLimit(tc.limiter, ClientRateLimit{Rate: 1, Burst: 5})
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+			r := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			r.RemoteAddr = "192.0.2.1:5000"
+			if tc.clientID != "" {
+				r = r.WithContext(withAPIKey(r.Context(), &ghsearch.APIKey{ID: tc.clientID}))
+			}
+			w := httptest.NewRecorder()
+			h.rateLimit(next).ServeHTTP(w, r)
+
+			if w.Code != tc.wantStatus {
+				t.Errorf("status: %d, want: %d", w.Code, tc.wantStatus)
+			}
+			if got := w.Header().Get(HeaderRateLimitRemaining); got != tc.wantRemaining {
+				t.Errorf("remaining: %q, want: %q", got, tc.wantRemaining)
+			}
+			if tc.limiter.key != tc.wantKey {
+				t.Errorf("key: %q, want: %q", tc.limiter.key, tc.wantKey)
+			}
+			if tc.wantRemaining != "" && w.Header().Get(HeaderRateLimitLimit) != strconv.Itoa(5) {
+				t.Errorf("limit: %q, want: 5", w.Header().Get(HeaderRateLimitLimit))
+			}
+			if tc.wantStatus == http.StatusTooManyRequests && w.Header().Get("Retry-After") != "1" {
+				t.Errorf("retry after: %q, want: 1", w.Header().Get("Retry-After"))
+			}
+		})
+	}
+}
+
+type mockedClientRateLimiter struct {
+	ok        bool
+	remaining int
+	err       error
+
+	key string
+}
+
+func (m *mockedClientRateLimiter) TakeToken(_ context.Context, key string, _ float64, _ int) (bool, int, error) {
+	m.key = key
+	return m.ok, m.remaining, m.err
+}
@@ -0,0 +1,126 @@
+package http
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/kudarap/ghsearch"
+)
+
+const problemContentType = "application/problem+json; charset=utf-8"
+
+// Machine-readable error codes, these are part of the API and should stay stable.
+const (
+	errCodeTooManyInput  = "too_many_input"
+	errCodeUserNotFound  = "user_not_found"
+	errCodeRateLimited   = "rate_limited"
+	errCodeSourceTimeout = "source_timeout"
+	errCodeSourceFailed  = "source_failed"
+	errCodeUnavailable   = "source_unavailable"
+	errCodeInternal      = "internal_error"
+	errCodeCacheMiss     = "cache_miss"
+	errCodeUnauthorized  = "unauthorized"
+	errCodeInvalidInput  = "invalid_input"
+	errCodeBypassLimited = "cache_bypass_limited"
+	errCodeClientLimited = "client_rate_limited"
+	errCodeForbidden     = "forbidden"
+	errCodeSourceUnauth  = "source_unauthorized"
+	errCodeInvalidUser   = "invalid_username"
+)
+
+var (
+	// errUnauthorized indicates request is missing valid credentials.
+	errUnauthorized = errors.New("missing or invalid token")
+
+	// errInvalidInput indicates request input is malformed or incomplete.
+	errInvalidInput = errors.New("invalid input")
+
+	// errCacheBypassLimited indicates too many requests are bypassing the cache.
+	errCacheBypassLimited = errors.New("cache bypass limit reached")
+)
+
+// problem represents an RFC 7807 problem details error response
+// with additional stable error code.
+type problem struct {
+	Type   string `json:"type"`
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+	Detail string `json:"detail,omitempty"`
+	Code   string `json:"code"`
+
+	// RequestID refers to the failed request on logs.
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// translateError maps domain errors to http status code and error code.
+func translateError(err error) (status int, code string) {
+	switch {
+	case errors.Is(err, ghsearch.ErrTooManyInput):
+		return http.StatusBadRequest, errCodeTooManyInput
+	case errors.Is(err, ghsearch.ErrUserNotFound):
+		return http.StatusNotFound, errCodeUserNotFound
+	case errors.Is(err, ghsearch.ErrInvalidUsername):
+		return http.StatusBadRequest, errCodeInvalidUser
+	case errors.Is(err, ghsearch.ErrCacheMiss):
+		return http.StatusNotFound, errCodeCacheMiss
+	case errors.Is(err, errUnauthorized):
+		return http.StatusUnauthorized, errCodeUnauthorized
+	case errors.Is(err, errForbidden):
+		return http.StatusForbidden, errCodeForbidden
+	case errors.Is(err, errInvalidInput):
+		return http.StatusBadRequest, errCodeInvalidInput
+	case errors.Is(err, errCacheBypassLimited):
+		return http.StatusTooManyRequests, errCodeBypassLimited
+	case errors.Is(err, errClientRateLimited):
+		return http.StatusTooManyRequests, errCodeClientLimited
+	case errors.Is(err, ghsearch.ErrUserSourceRateLimited):
+		return http.StatusTooManyRequests, errCodeRateLimited
+	case errors.Is(err, ghsearch.ErrUserSourceUnauthorized):
+		return http.StatusUnauthorized, errCodeSourceUnauth
+	case errors.Is(err, ghsearch.ErrUserSourceTimeout):
+		return http.StatusGatewayTimeout, errCodeSourceTimeout
+	case errors.Is(err, ghsearch.ErrUserSourceUnavailable):
+		return http.StatusServiceUnavailable, errCodeUnavailable
+	case errors.Is(err, ghsearch.ErrUserSourceFailed),
+		errors.As(err, new(*ghsearch.SourceError)):
+		return http.StatusBadGateway, errCodeSourceFailed
+	}
+	return http.StatusInternalServerError, errCodeInternal
+}
+
+// retryAfter returns seconds until rate limit resets, false when its not
+// rate limit error or reset time is unknown.
+func retryAfter(err error) (secs int, ok bool) {
+	var rle *ghsearch.RateLimitError
+	if !errors.As(err, &rle) || rle.ResetsAt.IsZero() {
+		return 0, false
+	}
+
+	secs = int(math.Ceil(time.Until(rle.ResetsAt).Seconds()))
+	if secs < 0 {
+		secs = 0
+	}
+	return secs, true
+}
+
+// encodeJSONError writes error as problem details with its translated status code.
+func encodeJSONError(w http.ResponseWriter, r *http.Request, err error) {
+	status, code := translateError(err)
+	if secs, ok := retryAfter(err); ok {
+		w.Header().Set("Retry-After", strconv.Itoa(secs))
+	}
+
+	p := problem{
+		Type:      "about:blank",
+		Title:     http.StatusText(status),
+		Status:    status,
+		Detail:    err.Error(),
+		Code:      code,
+		RequestID: ghsearch.RequestIDFrom(r.Context()),
+	}
+	w.Header().Set("Content-Type", problemContentType)
+	encodeJSON(w, p, status)
+}
@@ -0,0 +1,108 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kudarap/ghsearch"
+)
+
+func TestEncodeJSONError(t *testing.T) {
+	testcases := []struct {
+		name string
+		// args
+		err error
+		// returns
+		wantStatus     int
+		wantCode       string
+		wantRetryAfter string
+	}{
+		{
+			"too many input",
+			ghsearch.ErrTooManyInput,
+			http.StatusBadRequest,
+			errCodeTooManyInput,
+			"",
+		},
+		{
+			"invalid username",
+			ghsearch.ValidateUsername("-kudarap"),
+			http.StatusBadRequest,
+			errCodeInvalidUser,
+			"",
+		},
+		{
+			"rate limited",
+			ghsearch.NewRateLimitError(errors.New("rate limit"), time.Now().Add(time.Minute)),
+			http.StatusTooManyRequests,
+			errCodeRateLimited,
+			"60",
+		},
+		{
+			"rate limited unknown reset",
+			ghsearch.ErrUserSourceRateLimited,
+			http.StatusTooManyRequests,
+			errCodeRateLimited,
+			"",
+		},
+		{
+			"caller token rejected",
+			ghsearch.ErrUserSourceUnauthorized,
+			http.StatusUnauthorized,
+			errCodeSourceUnauth,
+			"",
+		},
+		{
+			"timed out",
+			ghsearch.ErrUserSourceTimeout,
+			http.StatusGatewayTimeout,
+			errCodeSourceTimeout,
+			"",
+		},
+		{
+			"source failed",
+			ghsearch.NewSourceError(errors.New("connection refused")),
+			http.StatusBadGateway,
+			errCodeSourceFailed,
+			"",
+		},
+		{
+			"unknown",
+			errors.New("unknown"),
+			http.StatusInternalServerError,
+			errCodeInternal,
+			"",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/users", nil)
+			r = r.WithContext(ghsearch.WithRequestID(r.Context(), "req-1"))
+			encodeJSONError(w, r, tc.err)
+			if w.Code != tc.wantStatus {
+				t.Errorf("status: %d, want: %d", w.Code, tc.wantStatus)
+			}
+			var p problem
+			if err := json.NewDecoder(w.Body).Decode(&p); err != nil {
+				t.Fatal(err)
+			}
+			if p.Code != tc.wantCode {
+				t.Errorf("code: %s, want: %s", p.Code, tc.wantCode)
+			}
+			if p.RequestID != "req-1" {
+				t.Errorf("request id: %s, want: %s", p.RequestID, "req-1")
+			}
+			if got := w.Header().Get("Retry-After"); got != tc.wantRetryAfter {
+				t.Errorf("retry-after: %s, want: %s", got, tc.wantRetryAfter)
+			}
+			if got := w.Header().Get("Content-Type"); got != problemContentType {
+				t.Errorf("content-type: %s, want: %s", got, problemContentType)
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// readinessTimeout limits how long dependency checks can take.
+const readinessTimeout = 3 * time.Second
+
+// Health statuses.
+const (
+	healthStatusOK           = "ok"
+	healthStatusFail         = "fail"
+	healthStatusShuttingDown = "shutting_down"
+)
+
+// ReadinessCheck checks if a dependency is ready, nil error when ready.
+type ReadinessCheck func(ctx context.Context) error
+
+// ReadinessReport checks if a dependency is ready like ReadinessCheck and
+// returns details of it shown on readiness response.
+type ReadinessReport func(ctx context.Context) (details interface{}, err error)
+
+// AddReadinessCheck registers dependency check of the readiness endpoint.
+func (h *RestHandler) AddReadinessCheck(name string, check ReadinessCheck) {
+	h.AddReadinessReport(name, func(ctx context.Context) (interface{}, error) {
+		return nil, check(ctx)
+	})
+}
+
+// AddReadinessReport registers dependency check with details of the readiness endpoint.
+func (h *RestHandler) AddReadinessReport(name string, report ReadinessReport) {
+	if h.readinessChecks == nil {
+		h.readinessChecks = map[string]ReadinessReport{}
+	}
+	h.readinessChecks[name] = report
+}
+
+// CacheReadinessReport reuses report result within expr so frequent probes
+// don't reach the dependency every time, concurrent probes share a single check.
+func CacheReadinessReport(report ReadinessReport, expr time.Duration) ReadinessReport {
+	var mu sync.Mutex
+	var details interface{}
+	var err error
+	var checkedAt time.Time
+	return func(ctx context.Context) (interface{}, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if checkedAt.IsZero() || time.Since(checkedAt) >= expr {
+			details, err = report(ctx)
+			checkedAt = time.Now()
+		}
+		return details, err
+	}
+}
+
+// healthResp represents health and readiness response.
+type healthResp struct {
+	Status string               `json:"status"`
+	Checks map[string]checkResp `json:"checks,omitempty"`
+}
+
+// checkResp represents a dependency readiness check result.
+type checkResp struct {
+	Status    string      `json:"status"`
+	LatencyMS float64     `json:"latency_ms"`
+	Details   interface{} `json:"details,omitempty"`
+	Error     string      `json:"error,omitempty"`
+}
+
+// healthz handles liveness requests, responding at all means the process is alive.
+func healthz() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		encodeJSONResp(w, healthResp{Status: healthStatusOK}, http.StatusOK)
+	}
+}
+
+// readyz handles readiness requests by running dependency checks concurrently,
+// its never ready once shutting down so no new traffic is routed in.
+func readyz(checks map[string]ReadinessReport, shuttingDown *int32) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(shuttingDown) == 1 {
+			encodeJSONResp(w, healthResp{Status: healthStatusShuttingDown}, http.StatusServiceUnavailable)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+		defer cancel()
+
+		resp := healthResp{Status: healthStatusOK, Checks: make(map[string]checkResp, len(checks))}
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+		for name, check := range checks {
+			name, check := name, check
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				start := time.Now()
+				details, err := check(ctx)
+				cr := checkResp{
+					Status:    healthStatusOK,
+					LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+					Details:   details,
+				}
+				if err != nil {
+					cr.Status, cr.Details, cr.Error = healthStatusFail, nil, err.Error()
+				}
+
+				mu.Lock()
+				defer mu.Unlock()
+				resp.Checks[name] = cr
+				if err != nil {
+					resp.Status = healthStatusFail
+				}
+			}()
+		}
+		wg.Wait()
+
+		code := http.StatusOK
+		if resp.Status != healthStatusOK {
+			code = http.StatusServiceUnavailable
+		}
+		encodeJSONResp(w, resp, code)
+	}
+}
@@ -0,0 +1,110 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestReadyz(t *testing.T) {
+	ok := func(ctx context.Context) (interface{}, error) { return nil, nil }
+	fail := func(ctx context.Context) (interface{}, error) { return nil, errors.New("connection refused") }
+	exhausted := func(ctx context.Context) (interface{}, error) { return map[string]int{"remaining": 0}, nil }
+	testcases := []struct {
+		name string
+		// deps
+		checks       map[string]ReadinessReport
+		shuttingDown int32
+		// returns
+		wantCode   int
+		wantStatus string
+		wantChecks map[string]string
+		// wantDetails names check expected to have details.
+		wantDetails string
+	}{
+		{
+			"ready",
+			map[string]ReadinessReport{"redis": ok, "github": ok},
+			0,
+			http.StatusOK,
+			healthStatusOK,
+			map[string]string{"redis": healthStatusOK, "github": healthStatusOK},
+			"",
+		},
+		{
+			"dependency failed",
+			map[string]ReadinessReport{"redis": fail, "github": ok},
+			0,
+			http.StatusServiceUnavailable,
+			healthStatusFail,
+			map[string]string{"redis": healthStatusFail, "github": healthStatusOK},
+			"",
+		},
+		{
+			"dependency details",
+			map[string]ReadinessReport{"redis": ok, "github": exhausted},
+			0,
+			http.StatusOK,
+			healthStatusOK,
+			map[string]string{"redis": healthStatusOK, "github": healthStatusOK},
+			"github",
+		},
+		{
+			"shutting down",
+			map[string]ReadinessReport{"redis": ok},
+			1,
+			http.StatusServiceUnavailable,
+			healthStatusShuttingDown,
+			map[string]string{},
+			"",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			readyz(tc.checks, &tc.shuttingDown).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+			if w.Code != tc.wantCode {
+				t.Errorf("code: %d, want: %d", w.Code, tc.wantCode)
+			}
+
+			var got healthResp
+			if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Status != tc.wantStatus {
+				t.Errorf("status: %s, want: %s", got.Status, tc.wantStatus)
+			}
+			for name, want := range tc.wantChecks {
+				if c := got.Checks[name]; c.Status != want {
+					t.Errorf("%s status: %s, want: %s", name, c.Status, want)
+				}
+			}
+			if name := tc.wantDetails; name != "" && got.Checks[name].Details == nil {
+				t.Errorf("%s details missing", name)
+			}
+		})
+	}
+}
+
+func TestCacheReadinessReport(t *testing.T) {
+	var calls int
+	report := CacheReadinessReport(func(ctx context.Context) (interface{}, error) {
+		calls++
+		return calls, nil
+	}, 50*time.Millisecond)
+
+	ctx := context.Background()
+	for _, want := range []int{1, 1} {
+		if got, _ := report(ctx); got != want {
+			t.Errorf("details: %v, want: %v", got, want)
+		}
+	}
+	time.Sleep(50 * time.Millisecond)
+	if got, _ := report(ctx); got != 2 {
+		t.Errorf("details: %v, want: %v", got, 2)
+	}
+}
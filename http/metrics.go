@@ -0,0 +1,49 @@
+package http
+
+import (
+	"net/http"
+	"time"
+
+	chimw "github.com/go-chi/chi/v5/middleware"
+	"github.com/gorilla/mux"
+)
+
+// Metrics provides http request instrumentation and exposition.
+type Metrics interface {
+	ObserveRequest(route, method string, status int, d time.Duration)
+	APIKeyRequest(key, route string, status int)
+	Handler() http.Handler
+}
+
+// SetMetrics enables request metrics and metrics endpoint.
+func (h *RestHandler) SetMetrics(m Metrics) {
+	h.metrics = m
+}
+
+// instrument records request count and latency by route template
+// to keep label cardinality bounded.
+func instrument(m Metrics) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			ww := chimw.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r)
+
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			m.ObserveRequest(routeTemplate(r), r.Method, status, time.Since(start))
+		})
+	}
+}
+
+// routeTemplate returns matched route template, or the path when unmatched.
+func routeTemplate(r *http.Request) string {
+	if cr := mux.CurrentRoute(r); cr != nil {
+		if tpl, err := cr.GetPathTemplate(); err == nil {
+			return tpl
+		}
+	}
+	return r.URL.Path
+}
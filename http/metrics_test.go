@@ -0,0 +1,46 @@
+package http
+
+import (
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/kudarap/ghsearch"
+)
+
+func TestInstrument(t *testing.T) {
+	m := &mockedMetrics{}
+	rest := NewRestHandler(nil)
+	rest.SetAdminToken("secret")
+	rest.SetCacheAdmin(&mockedCacheAdmin{users: map[string]*ghsearch.User{}})
+	rest.SetMetrics(m)
+	srv := NewServer("", rest, log.New(ioutil.Discard, "", 0))
+
+	r := httptest.NewRequest(http.MethodGet, "/admin/cache/users/kudarap", nil)
+	srv.srv.Handler.ServeHTTP(httptest.NewRecorder(), r)
+
+	want := "/admin/cache/users/{username} GET 401"
+	if m.last != want {
+		t.Errorf("got: %s, want: %s", m.last, want)
+	}
+}
+
+type mockedMetrics struct {
+	last string
+}
+
+func (m *mockedMetrics) ObserveRequest(route, method string, status int, d time.Duration) {
+	m.last = route + " " + method + " " + strconv.Itoa(status)
+}
+
+func (m *mockedMetrics) APIKeyRequest(key, route string, status int) {
+	m.last = key + " " + route + " " + strconv.Itoa(status)
+}
+
+func (m *mockedMetrics) Handler() http.Handler {
+	return http.NotFoundHandler()
+}
@@ -0,0 +1,21 @@
+package http
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+// openAPISpec is the OpenAPI 3 document of every endpoint NewServer can route,
+// TestNewServer_OpenAPI keeps both in sync.
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
+// openAPI handles OpenAPI document requests.
+func openAPI() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		w.WriteHeader(http.StatusOK)
+		w.Write(openAPISpec)
+	}
+}
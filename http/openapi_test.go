@@ -0,0 +1,153 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/kudarap/ghsearch"
+	"github.com/kudarap/ghsearch/client"
+)
+
+func TestNewServer_OpenAPI(t *testing.T) {
+	rest := NewRestHandler(&policyUserService{})
+	rest.SetMetrics(&mockedMetrics{})
+	rest.SetCircuitBreaker(ghsearch.NewCircuitBreaker(nil, ghsearch.DefaultCircuitBreakerConfig))
+	rest.SetAdminToken("secret")
+	rest.SetCacheAdmin(&mockedCacheAdmin{})
+	rest.SetAPIKeys(mockedAPIKeyStore{})
+	rest.SetClientRateLimit(&mockedClientRateLimiter{ok: true}, ClientRateLimit{})
+	srv := NewServer("", rest, log.New(ioutil.Discard, "", 0))
+
+	var routes []string
+	err := srv.srv.Handler.(*mux.Router).Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		methods, err := route.GetMethods()
+		if err != nil {
+			// Prefixes like /admin has no handler of its own.
+			return nil
+		}
+		path, err := route.GetPathTemplate()
+		if err != nil {
+			return err
+		}
+		for _, m := range methods {
+			routes = append(routes, path+" "+m)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal("walk routes:", err)
+	}
+
+	var spec struct {
+		Paths      map[string]map[string]json.RawMessage `json:"paths"`
+		Components struct {
+			Schemas struct {
+				Problem struct {
+					Properties struct {
+						Code struct {
+							Enum []string `json:"enum"`
+						} `json:"code"`
+					} `json:"properties"`
+				} `json:"Problem"`
+			} `json:"schemas"`
+		} `json:"components"`
+	}
+	if err = json.Unmarshal(openAPISpec, &spec); err != nil {
+		t.Fatal("decode spec:", err)
+	}
+	var ops []string
+	for path, item := range spec.Paths {
+		for method := range item {
+			// Path item also holds its shared parameters.
+			if method == "parameters" {
+				continue
+			}
+			ops = append(ops, path+" "+strings.ToUpper(method))
+		}
+	}
+
+	sort.Strings(routes)
+	sort.Strings(ops)
+	if !reflect.DeepEqual(routes, ops) {
+		t.Errorf("routes and spec differ\ngot: \n\t%#v \nwant: \n\t%#v", routes, ops)
+	}
+
+	// Error codes translated by the server, one error of each case.
+	codeSet := map[string]bool{}
+	for _, err := range []error{
+		ghsearch.ErrTooManyInput,
+		ghsearch.ErrUserNotFound,
+		ghsearch.ErrInvalidUsername,
+		ghsearch.ErrCacheMiss,
+		errUnauthorized,
+		errForbidden,
+		errInvalidInput,
+		errCacheBypassLimited,
+		errClientRateLimited,
+		ghsearch.ErrUserSourceRateLimited,
+		ghsearch.ErrUserSourceUnauthorized,
+		ghsearch.ErrUserSourceTimeout,
+		ghsearch.ErrUserSourceUnavailable,
+		ghsearch.ErrUserSourceFailed,
+		errors.New("unknown"),
+	} {
+		_, code := translateError(err)
+		codeSet[code] = true
+	}
+	var codes []string
+	for code := range codeSet {
+		codes = append(codes, code)
+	}
+	clientCodes := []string{
+		client.CodeTooManyInput,
+		client.CodeUserNotFound,
+		client.CodeRateLimited,
+		client.CodeSourceTimeout,
+		client.CodeSourceFailed,
+		client.CodeSourceUnavailable,
+		client.CodeInternal,
+		client.CodeCacheMiss,
+		client.CodeUnauthorized,
+		client.CodeInvalidInput,
+		client.CodeCacheBypassLimited,
+		client.CodeClientRateLimited,
+		client.CodeForbidden,
+		client.CodeSourceUnauthorized,
+		client.CodeInvalidUsername,
+	}
+	specCodes := spec.Components.Schemas.Problem.Properties.Code.Enum
+
+	sort.Strings(codes)
+	sort.Strings(clientCodes)
+	sort.Strings(specCodes)
+	if !reflect.DeepEqual(codes, specCodes) {
+		t.Errorf("error codes and spec differ\ngot: \n\t%#v \nwant: \n\t%#v", codes, specCodes)
+	}
+	if !reflect.DeepEqual(clientCodes, specCodes) {
+		t.Errorf("client codes and spec differ\ngot: \n\t%#v \nwant: \n\t%#v", clientCodes, specCodes)
+	}
+}
+
+func TestNewServer_OpenAPIHandler(t *testing.T) {
+	rest := NewRestHandler(&policyUserService{})
+	rest.SetAPIKeys(mockedAPIKeyStore{})
+	srv := NewServer("", rest, log.New(ioutil.Discard, "", 0))
+	w := httptest.NewRecorder()
+	srv.srv.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+
+	if w.Code != http.StatusOK {
+		t.Errorf("status: %d, want: %d", w.Code, http.StatusOK)
+	}
+	if !json.Valid(w.Body.Bytes()) {
+		t.Error("spec is not valid json")
+	}
+}
@@ -0,0 +1,55 @@
+package http
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+
+	chimw "github.com/go-chi/chi/v5/middleware"
+	"github.com/kudarap/ghsearch"
+)
+
+// HeaderRequestID is a request and response header key of request id.
+const HeaderRequestID = "X-Request-ID"
+
+// maxRequestIDLength prevents clients from flooding logs with long ids.
+const maxRequestIDLength = 128
+
+// requestID accepts incoming request id or generates a new one, its set on
+// response header and context so logs and upstream requests can refer to it.
+func requestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(HeaderRequestID)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		w.Header().Set(HeaderRequestID, id)
+
+		ctx := ghsearch.WithRequestID(r.Context(), id)
+		// Request logger prints request id from its own context key.
+		ctx = context.WithValue(ctx, chimw.RequestIDKey, id)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// validRequestID allows printable ASCII without spaces.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,47 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kudarap/ghsearch"
+)
+
+func TestRequestID(t *testing.T) {
+	testcases := []struct {
+		name string
+		// args
+		incoming string
+		// returns
+		wantKept bool
+	}{
+		{"accepts incoming", "req-1", true},
+		{"generates when missing", "", false},
+		{"replaces invalid", "bad id\n", false},
+		{"replaces too long", strings.Repeat("a", maxRequestIDLength+1), false},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			var ctxID string
+			h := requestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				ctxID = ghsearch.RequestIDFrom(r.Context())
+			}))
+			r := httptest.NewRequest(http.MethodGet, "/users", nil)
+			if tc.incoming != "" {
+				r.Header.Set(HeaderRequestID, tc.incoming)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			got := w.Header().Get(HeaderRequestID)
+			if got == "" || got != ctxID {
+				t.Errorf("header: %q, context: %q", got, ctxID)
+			}
+			if kept := got == tc.incoming; kept != tc.wantKept {
+				t.Errorf("request id: %q, incoming: %q", got, tc.incoming)
+			}
+		})
+	}
+}
@@ -2,6 +2,8 @@ package http
 
 import (
 	"encoding/json"
+	"errors"
+	"net"
 	"net/http"
 	"strings"
 
@@ -10,48 +12,190 @@ import (
 
 const contentType = "application/json; charset=utf-8"
 
+// HeaderGithubToken is a request header key of caller own GitHub access token
+// used instead of the service tokens, its never logged nor used as a cache key.
+const HeaderGithubToken = "X-GitHub-Token"
+
+// headerCacheStale indicates some users on the response are served from outdated cache.
+const headerCacheStale = "X-Cache-Stale"
+
+// userStatusOK represents a successful lookup status of users response item,
+// failed lookups uses its error code as status.
+const userStatusOK = "ok"
+
+// CircuitBreaker provides circuit breaker status.
+type CircuitBreaker interface {
+	Status() ghsearch.CircuitStatus
+}
+
 // RestHandler represents http rest handler.
 type RestHandler struct {
 	userSvc ghsearch.UserService
+
+	// circuit is optional, status endpoint is disabled when nil.
+	circuit CircuitBreaker
+
+	// cacheAdmin is optional, admin endpoints are disabled when nil.
+	cacheAdmin CacheAdmin
+	adminToken string
+
+	// bypassLimiter is optional, requests that skip the cache are not limited when nil.
+	bypassLimiter ClientRateLimiter
+	bypassLimit   int
+
+	// metrics is optional, metrics endpoint is disabled when nil.
+	metrics Metrics
+
+	// apiKeys is optional, requests are anonymous when nil.
+	apiKeys     ghsearch.APIKeyStore
+	apiKeyUsage *apiKeyUsage
+
+	// clientLimiter is optional, requests are not limited per client when nil.
+	clientLimiter   ClientRateLimiter
+	clientRateLimit ClientRateLimit
+	trustedProxies  []*net.IPNet
+
+	// readinessChecks are dependencies checked by readiness endpoint.
+	readinessChecks map[string]ReadinessReport
 }
 
 // GETUsers handles users search requests.
 func (h *RestHandler) GETUsers() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		ctx := r.Context()
 		usernames := r.URL.Query().Get("usernames")
 		if strings.TrimSpace(usernames) == "" {
 			encodeJSONResp(w, make([]struct{}, 0), http.StatusOK)
 			return
 		}
 
-		splits := strings.Split(usernames, ",")
-		users, err := h.userSvc.Users(ctx, splits)
+		policy, err := cachePolicyFrom(r)
 		if err != nil {
-			encodeJSONError(w, err, http.StatusBadRequest)
+			encodeJSONError(w, r, err)
+			return
+		}
+		h.users(w, r, strings.Split(usernames, ","), policy, nil)
+	}
+}
+
+// users looks up usernames and responds with each result, fields selects
+// user fields to respond with or all when empty.
+func (h *RestHandler) users(w http.ResponseWriter, r *http.Request, usernames []string, policy ghsearch.CachePolicy, fields []string) {
+	if policy.Bypass() {
+		if err := h.allowCacheBypass(r); err != nil {
+			encodeJSONError(w, r, err)
 			return
 		}
+	}
+	ctx := ghsearch.WithCachePolicy(r.Context(), policy)
+	if token := strings.TrimSpace(r.Header.Get(HeaderGithubToken)); token != "" {
+		ctx = ghsearch.WithSourceToken(ctx, token)
+	}
 
-		encodeJSONResp(w, users, http.StatusOK)
+	results, err := h.userSvc.Users(ctx, usernames)
+	if err != nil {
+		encodeJSONError(w, r, err)
+		return
+	}
+	// Nothing usable to return when every lookup failed upstream,
+	// respond with the error so clients can decide to retry.
+	if err = upstreamFailure(results); err != nil {
+		encodeJSONError(w, r, err)
+		return
+	}
+
+	if hasStale(results) {
+		w.Header().Set(headerCacheStale, "true")
+	}
+	encodeJSONResp(w, newUserResultsResp(results, fields), http.StatusOK)
+}
+
+// GETCircuitStatus handles user source circuit breaker status requests.
+func (h *RestHandler) GETCircuitStatus() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		encodeJSONResp(w, h.circuit.Status(), http.StatusOK)
 	}
 }
 
+// SetCircuitBreaker enables circuit breaker status endpoint.
+func (h *RestHandler) SetCircuitBreaker(cb CircuitBreaker) {
+	h.circuit = cb
+}
+
 // NewRestHandler creates new http rest handler.
 func NewRestHandler(us ghsearch.UserService) *RestHandler {
-	return &RestHandler{us}
+	return &RestHandler{userSvc: us}
+}
+
+// userResultResp represents a single item of users response.
+type userResultResp struct {
+	Username string `json:"username"`
+	Status   string `json:"status"`
+	// User is either *ghsearch.User or its selected fields.
+	User  interface{} `json:"user,omitempty"`
+	Stale bool        `json:"stale,omitempty"`
+	Error string      `json:"error,omitempty"`
+}
+
+func newUserResultsResp(results []ghsearch.UserResult, fields []string) []userResultResp {
+	resp := make([]userResultResp, len(results))
+	for i, r := range results {
+		resp[i] = userResultResp{
+			Username: r.Username,
+			Status:   userResultStatus(r.Err),
+			Stale:    r.Stale,
+		}
+		if r.User != nil {
+			resp[i].User = selectUserFields(r.User, fields)
+		}
+		if r.Err != nil {
+			resp[i].Error = r.Err.Error()
+		}
+	}
+	return resp
+}
+
+func userResultStatus(err error) string {
+	if err == nil {
+		return userStatusOK
+	}
+	_, code := translateError(err)
+	return code
+}
+
+func hasStale(results []ghsearch.UserResult) bool {
+	for _, r := range results {
+		if r.Stale {
+			return true
+		}
+	}
+	return false
+}
+
+// upstreamFailure returns the first error when all results failed
+// for other reason than user not found or invalid username.
+func upstreamFailure(results []ghsearch.UserResult) error {
+	var first error
+	for _, r := range results {
+		if r.Err == nil ||
+			errors.Is(r.Err, ghsearch.ErrUserNotFound) ||
+			errors.Is(r.Err, ghsearch.ErrInvalidUsername) {
+			return nil
+		}
+		if first == nil {
+			first = r.Err
+		}
+	}
+	return first
 }
 
 func encodeJSONResp(w http.ResponseWriter, data interface{}, code int) {
 	w.Header().Set("Content-Type", contentType)
+	encodeJSON(w, data, code)
+}
+
+func encodeJSON(w http.ResponseWriter, data interface{}, code int) {
 	w.WriteHeader(code)
 	if err := json.NewEncoder(w).Encode(data); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 	}
 }
-
-func encodeJSONError(w http.ResponseWriter, err error, code int) {
-	m := struct {
-		Error string `json:"error"`
-	}{err.Error()}
-	encodeJSONResp(w, m, code)
-}
@@ -0,0 +1,97 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/kudarap/ghsearch"
+)
+
+func TestRestHandler_GETUsers_GithubToken(t *testing.T) {
+	testcases := []struct {
+		name string
+		// args
+		header string
+		// returns
+		want string
+	}{
+		{"service tokens", "", ""},
+		{"caller token", " ghp_caller ", "ghp_caller"},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			svc := &tokenUserService{}
+			r := httptest.NewRequest(http.MethodGet, "/users?usernames=kudarap", nil)
+			if tc.header != "" {
+				r.Header.Set(HeaderGithubToken, tc.header)
+			}
+			w := httptest.NewRecorder()
+			NewRestHandler(svc).GETUsers().ServeHTTP(w, r)
+			if svc.token != tc.want {
+				t.Errorf("token: %q, want: %q", svc.token, tc.want)
+			}
+		})
+	}
+}
+
+func TestRestHandler_GETUsers_InvalidUsername(t *testing.T) {
+	testcases := []struct {
+		name string
+		// args
+		usernames string
+		// returns
+		want []userResultResp
+	}{
+		{
+			"some invalid",
+			"kudarap,-bad-",
+			[]userResultResp{
+				{Username: "kudarap", Status: userStatusOK, User: map[string]interface{}{
+					"name": "", "login": "kudarap", "company": "", "followers": 0.0, "public_repos": 0.0,
+				}},
+				{Username: "-bad-", Status: errCodeInvalidUser, Error: ghsearch.ValidateUsername("-bad-").Error()},
+			},
+		},
+		{
+			"all invalid",
+			"a--b",
+			[]userResultResp{
+				{Username: "a--b", Status: errCodeInvalidUser, Error: ghsearch.ValidateUsername("a--b").Error()},
+			},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			svc := ghsearch.NewUserService(ghsearch.UserSourceFunc(func(_ context.Context, username string) (*ghsearch.User, error) {
+				return &ghsearch.User{Login: username}, nil
+			}))
+			r := httptest.NewRequest(http.MethodGet, "/users?usernames="+tc.usernames, nil)
+			w := httptest.NewRecorder()
+			NewRestHandler(svc).GETUsers().ServeHTTP(w, r)
+			if w.Code != http.StatusOK {
+				t.Fatalf("status: %d, want: %d", w.Code, http.StatusOK)
+			}
+
+			var got []userResultResp
+			if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+				t.Fatal("decode:", err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("\ngot: \n\t%#v \nwant: \n\t%#v", got, tc.want)
+			}
+		})
+	}
+}
+
+type tokenUserService struct {
+	token string
+}
+
+func (s *tokenUserService) Users(ctx context.Context, usernames []string) ([]ghsearch.UserResult, error) {
+	s.token = ghsearch.SourceTokenFrom(ctx)
+	return nil, nil
+}
@@ -5,11 +5,13 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	chimw "github.com/go-chi/chi/v5/middleware"
 	"github.com/gorilla/mux"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gorilla/mux/otelmux"
 )
 
 const (
@@ -17,12 +19,27 @@ const (
 	readWriteTimeout = 15 * time.Second
 
 	defaultAddr = ":8080"
+
+	// serverName identifies server spans.
+	serverName = "gh-search"
 )
 
 // Server represents http server.
 type Server struct {
 	srv    *http.Server
 	logger logger
+
+	// shuttingDown is set once shutdown starts to fail readiness.
+	shuttingDown int32
+
+	// drainDelay keeps serving after failing readiness so load balancers
+	// can stop routing traffic before connections are closed.
+	drainDelay time.Duration
+}
+
+// SetDrainDelay sets how long to keep serving after shutdown starts.
+func (s *Server) SetDrainDelay(d time.Duration) {
+	s.drainDelay = d
 }
 
 // Run starts serving and listening http server with graceful shutdown.
@@ -38,6 +55,12 @@ func (s *Server) gracefulShutdown(srv *http.Server, timeout time.Duration) error
 		signal.Notify(c, os.Interrupt, syscall.SIGTERM)
 		<-c
 
+		atomic.StoreInt32(&s.shuttingDown, 1)
+		if s.drainDelay > 0 {
+			s.logger.Println("http server: draining for", s.drainDelay)
+			time.Sleep(s.drainDelay)
+		}
+
 		ctx := context.Background()
 		var cancel context.CancelFunc
 		if timeout > 0 {
@@ -63,18 +86,52 @@ func NewServer(addr string, rest *RestHandler, l logger) *Server {
 		addr = defaultAddr
 	}
 
+	s := &Server{logger: l}
 	r := mux.NewRouter()
+	// Continues incoming W3C trace context, spans are only recorded
+	// when a tracer provider is set.
+	r.Use(otelmux.Middleware(serverName))
+	r.Use(requestID)
 	r.Use(structuredLogger(l))
+	if rest.metrics != nil {
+		r.Use(instrument(rest.metrics))
+		r.Handle("/metrics", rest.metrics.Handler()).Methods(http.MethodGet)
+	}
+	// Authenticated and limited after logging and metrics so rejected
+	// requests are still observed.
+	if rest.apiKeys != nil {
+		r.Use(rest.apiKeyAuth)
+	}
+	if rest.clientLimiter != nil {
+		r.Use(rest.rateLimit)
+	}
 	r.Handle("/users", rest.GETUsers()).Methods(http.MethodGet)
+	r.Handle("/users/search", rest.POSTUsersSearch()).Methods(http.MethodPost)
+	r.Handle("/healthz", healthz()).Methods(http.MethodGet)
+	r.Handle("/readyz", readyz(rest.readinessChecks, &s.shuttingDown)).Methods(http.MethodGet)
+	r.Handle("/openapi.json", openAPI()).Methods(http.MethodGet)
+	if rest.circuit != nil {
+		r.Handle("/status/circuit-breaker", rest.GETCircuitStatus()).Methods(http.MethodGet)
+	}
+	if rest.adminToken != "" {
+		admin := r.PathPrefix("/admin").Subrouter()
+		admin.Use(rest.adminAuth)
+		if rest.cacheAdmin != nil {
+			admin.Handle("/cache/users/{username}", rest.GETCachedUser()).Methods(http.MethodGet)
+			admin.Handle("/cache/users/{username}", rest.DELETECachedUser()).Methods(http.MethodDelete)
+			admin.Handle("/cache/users/{username}/refresh", rest.POSTRefreshCachedUser()).Methods(http.MethodPost)
+			admin.Handle("/cache/keys", rest.DELETECacheKeys()).Methods(http.MethodDelete)
+		}
+		if rest.apiKeys != nil {
+			admin.Handle("/api-keys/usage", rest.GETAPIKeyUsage()).Methods(http.MethodGet)
+		}
+	}
 
-	s := &Server{
-		srv: &http.Server{
-			Handler:      r,
-			Addr:         addr,
-			WriteTimeout: readWriteTimeout,
-			ReadTimeout:  readWriteTimeout,
-		},
-		logger: l,
+	s.srv = &http.Server{
+		Handler:      r,
+		Addr:         addr,
+		WriteTimeout: readWriteTimeout,
+		ReadTimeout:  readWriteTimeout,
 	}
 	return s
 }
@@ -85,5 +142,5 @@ type logger interface {
 }
 
 func structuredLogger(l logger) func(next http.Handler) http.Handler {
-	return chimw.RequestLogger(&chimw.DefaultLogFormatter{Logger: l})
+	return chimw.RequestLogger(&apiKeyLogFormatter{&chimw.DefaultLogFormatter{Logger: l}})
 }
@@ -0,0 +1,46 @@
+package http
+
+import (
+	"context"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kudarap/ghsearch"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestNewServer_TracePropagation(t *testing.T) {
+	prevTP, prevProp := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	otel.SetTracerProvider(sdktrace.NewTracerProvider())
+	t.Cleanup(func() {
+		otel.SetTracerProvider(prevTP)
+		otel.SetTextMapPropagator(prevProp)
+	})
+
+	svc := &traceUserService{}
+	srv := NewServer("", NewRestHandler(svc), log.New(ioutil.Discard, "", 0))
+	r := httptest.NewRequest(http.MethodGet, "/users?usernames=kudarap", nil)
+	r.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	srv.srv.Handler.ServeHTTP(httptest.NewRecorder(), r)
+
+	want := "4bf92f3577b34da6a3ce929d0e0e4736"
+	if got := svc.traceID.String(); got != want {
+		t.Errorf("trace id: %s, want: %s", got, want)
+	}
+}
+
+type traceUserService struct {
+	traceID trace.TraceID
+}
+
+func (s *traceUserService) Users(ctx context.Context, usernames []string) ([]ghsearch.UserResult, error) {
+	s.traceID = trace.SpanContextFromContext(ctx).TraceID()
+	return nil, nil
+}
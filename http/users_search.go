@@ -0,0 +1,125 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/kudarap/ghsearch"
+)
+
+const (
+	// maxSearchBodySize limits users search request body.
+	maxSearchBodySize = 64 << 10
+
+	// maxSearchTimeout keeps requested timeout within server write timeout.
+	maxSearchTimeout = 10 * time.Second
+)
+
+// userFields are selectable user fields, struct field index by its JSON name.
+var userFields = jsonFields(reflect.TypeOf(ghsearch.User{}))
+
+// jsonFields maps JSON names of struct type t to its field index.
+func jsonFields(t reflect.Type) map[string]int {
+	fields := map[string]int{}
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+		fields[name] = i
+	}
+	return fields
+}
+
+// usersSearchReq represents users search request body.
+type usersSearchReq struct {
+	Usernames []string `json:"usernames"`
+	// Fields selects user fields to respond with, all when empty.
+	Fields []string `json:"fields,omitempty"`
+	// Cache overrides Cache-Control header when set.
+	Cache *cachePolicyReq `json:"cache,omitempty"`
+	// TimeoutMS limits the lookups in milliseconds, source default when zero.
+	TimeoutMS int `json:"timeout_ms,omitempty"`
+}
+
+// cachePolicyReq represents cache policy of users search request.
+type cachePolicyReq struct {
+	NoCache bool `json:"no_cache,omitempty"`
+	// MaxAge in seconds same as max-age directive, cache default when not set.
+	MaxAge *int `json:"max_age,omitempty"`
+}
+
+// validate checks request against its schema, usernames count is left
+// to the service so its consistent with other users endpoints.
+func (req *usersSearchReq) validate() error {
+	if len(req.Usernames) == 0 {
+		return fmt.Errorf("%w: usernames required", errInvalidInput)
+	}
+	for _, f := range req.Fields {
+		if _, ok := userFields[f]; !ok {
+			return fmt.Errorf("%w: unknown field %q", errInvalidInput, f)
+		}
+	}
+	if req.TimeoutMS < 0 || int64(req.TimeoutMS) > maxSearchTimeout.Milliseconds() {
+		return fmt.Errorf("%w: timeout_ms must be between 0 and %d", errInvalidInput, maxSearchTimeout.Milliseconds())
+	}
+	return nil
+}
+
+// cachePolicy returns cache policy of the request body, falls back to
+// Cache-Control header of r when its not set.
+func (req *usersSearchReq) cachePolicy(r *http.Request) (ghsearch.CachePolicy, error) {
+	if req.Cache == nil {
+		return cachePolicyFrom(r)
+	}
+	return newCachePolicy(req.Cache.NoCache, req.Cache.MaxAge)
+}
+
+// POSTUsersSearch handles users search requests with JSON body,
+// responds the same as GETUsers.
+func (h *RestHandler) POSTUsersSearch() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req usersSearchReq
+		dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxSearchBodySize))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&req); err != nil {
+			encodeJSONError(w, r, fmt.Errorf("%w: %s", errInvalidInput, err))
+			return
+		}
+		if err := req.validate(); err != nil {
+			encodeJSONError(w, r, err)
+			return
+		}
+
+		policy, err := req.cachePolicy(r)
+		if err != nil {
+			encodeJSONError(w, r, err)
+			return
+		}
+		if req.TimeoutMS > 0 {
+			ctx, cancel := context.WithTimeout(r.Context(), time.Duration(req.TimeoutMS)*time.Millisecond)
+			defer cancel()
+			r = r.WithContext(ctx)
+		}
+		h.users(w, r, req.Usernames, policy, req.Fields)
+	}
+}
+
+// selectUserFields returns user with only the fields, all when empty.
+func selectUserFields(u *ghsearch.User, fields []string) interface{} {
+	if len(fields) == 0 {
+		return u
+	}
+
+	v := reflect.ValueOf(u).Elem()
+	selected := make(map[string]interface{}, len(fields))
+	for _, f := range fields {
+		selected[f] = v.Field(userFields[f]).Interface()
+	}
+	return selected
+}
@@ -0,0 +1,132 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kudarap/ghsearch"
+)
+
+func TestRestHandler_POSTUsersSearch(t *testing.T) {
+	testcases := []struct {
+		name string
+		// args
+		body        string
+		cacheHeader string
+		// returns
+		wantStatus int
+		wantPolicy ghsearch.CachePolicy
+		wantUser   interface{}
+	}{
+		{
+			"all fields",
+			`{"usernames": ["kudarap"]}`,
+			"",
+			http.StatusOK,
+			ghsearch.CachePolicy{},
+			map[string]interface{}{"name": "", "login": "kudarap", "company": "", "followers": 0.0, "public_repos": 0.0},
+		},
+		{
+			"selected fields",
+			`{"usernames": ["kudarap"], "fields": ["login"], "timeout_ms": 500}`,
+			"",
+			http.StatusOK,
+			ghsearch.CachePolicy{},
+			map[string]interface{}{"login": "kudarap"},
+		},
+		{
+			"body cache policy",
+			`{"usernames": ["kudarap"], "cache": {"max_age": 1}}`,
+			"no-cache",
+			http.StatusOK,
+			ghsearch.CachePolicy{MaxAge: time.Second},
+			map[string]interface{}{"name": "", "login": "kudarap", "company": "", "followers": 0.0, "public_repos": 0.0},
+		},
+		{
+			"header cache policy",
+			`{"usernames": ["kudarap"], "fields": ["login"]}`,
+			"no-cache",
+			http.StatusOK,
+			ghsearch.CachePolicy{NoCache: true},
+			map[string]interface{}{"login": "kudarap"},
+		},
+		{"malformed", `{"usernames": "kudarap"}`, "", http.StatusBadRequest, ghsearch.CachePolicy{}, nil},
+		{"unknown property", `{"usernames": ["kudarap"], "limit": 1}`, "", http.StatusBadRequest, ghsearch.CachePolicy{}, nil},
+		{"missing usernames", `{}`, "", http.StatusBadRequest, ghsearch.CachePolicy{}, nil},
+		{"unknown field", `{"usernames": ["kudarap"], "fields": ["email"]}`, "", http.StatusBadRequest, ghsearch.CachePolicy{}, nil},
+		{"zero max age", `{"usernames": ["kudarap"], "cache": {"max_age": 0}}`, "", http.StatusOK, ghsearch.CachePolicy{NoCache: true}, map[string]interface{}{"name": "", "login": "kudarap", "company": "", "followers": 0.0, "public_repos": 0.0}},
+		{"negative max age", `{"usernames": ["kudarap"], "cache": {"max_age": -1}}`, "", http.StatusBadRequest, ghsearch.CachePolicy{}, nil},
+		{"long timeout", `{"usernames": ["kudarap"], "timeout_ms": 60000}`, "", http.StatusBadRequest, ghsearch.CachePolicy{}, nil},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			svc := &policyUserService{}
+			r := httptest.NewRequest(http.MethodPost, "/users/search", strings.NewReader(tc.body))
+			if tc.cacheHeader != "" {
+				r.Header.Set("Cache-Control", tc.cacheHeader)
+			}
+			w := httptest.NewRecorder()
+			NewRestHandler(svc).POSTUsersSearch().ServeHTTP(w, r)
+			if w.Code != tc.wantStatus {
+				t.Fatalf("status: %d, want: %d", w.Code, tc.wantStatus)
+			}
+			if w.Code != http.StatusOK {
+				var p problem
+				if err := json.NewDecoder(w.Body).Decode(&p); err != nil {
+					t.Fatal(err)
+				}
+				if p.Code != errCodeInvalidInput {
+					t.Errorf("code: %s, want: %s", p.Code, errCodeInvalidInput)
+				}
+				return
+			}
+
+			if !reflect.DeepEqual(svc.policy, tc.wantPolicy) {
+				t.Errorf("\ngot: \n\t%#v \nwant: \n\t%#v", svc.policy, tc.wantPolicy)
+			}
+			var got []struct {
+				Username string      `json:"username"`
+				Status   string      `json:"status"`
+				User     interface{} `json:"user"`
+			}
+			if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != 1 || got[0].Status != userStatusOK {
+				t.Fatalf("results: %#v", got)
+			}
+			if !reflect.DeepEqual(got[0].User, tc.wantUser) {
+				t.Errorf("\ngot: \n\t%#v \nwant: \n\t%#v", got[0].User, tc.wantUser)
+			}
+		})
+	}
+}
+
+func TestUsersSearchReq_validate_Timeout(t *testing.T) {
+	req := usersSearchReq{Usernames: []string{"kudarap"}, TimeoutMS: int(maxSearchTimeout / time.Millisecond)}
+	if err := req.validate(); err != nil {
+		t.Errorf("err: %#v, want: nil", err)
+	}
+	for _, ms := range []int{-1, math.MaxInt} {
+		req.TimeoutMS = ms
+		if err := req.validate(); !errors.Is(err, errInvalidInput) {
+			t.Errorf("timeout %d err: %#v, want: %#v", ms, err, errInvalidInput)
+		}
+	}
+}
+
+func TestSelectUserFields(t *testing.T) {
+	u := &ghsearch.User{Name: "Javin", Login: "kudarap", Company: "ghsearch", Followers: 1, PublicRepos: 2}
+	got := selectUserFields(u, []string{"name", "login", "company", "followers", "public_repos"})
+	want := map[string]interface{}{"name": "Javin", "login": "kudarap", "company": "ghsearch", "followers": 1, "public_repos": 2}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("\ngot: \n\t%#v \nwant: \n\t%#v", got, want)
+	}
+}
@@ -1,6 +1,7 @@
 package logging
 
 import (
+	"github.com/kudarap/ghsearch"
 	"github.com/sirupsen/logrus"
 	prefixed "github.com/x-cray/logrus-prefixed-formatter"
 )
@@ -14,5 +15,24 @@ func New() *Logger {
 	tf := &prefixed.TextFormatter{}
 	tf.FullTimestamp = true
 	logger.Formatter = tf
+	logger.AddHook(requestIDHook{})
 	return &Logger{logger}
 }
+
+// requestIDHook adds request id to entries logged with a request context
+// using WithContext.
+type requestIDHook struct{}
+
+func (requestIDHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (requestIDHook) Fire(e *logrus.Entry) error {
+	if e.Context == nil {
+		return nil
+	}
+	if id := ghsearch.RequestIDFrom(e.Context); id != "" {
+		e.Data["request_id"] = id
+	}
+	return nil
+}
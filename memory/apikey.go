@@ -0,0 +1,100 @@
+package memory
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"time"
+
+	"github.com/kudarap/ghsearch"
+)
+
+// DefaultAPIKeyCacheExpr sets how long API keys are cached in memory,
+// changed or removed keys on the store takes effect after it.
+const DefaultAPIKeyCacheExpr = 30 * time.Second
+
+// DefaultAPIKeyNotFoundCacheExpr sets how long unknown API keys are cached
+// in memory, kept short so newly added keys are accepted soon.
+const DefaultAPIKeyNotFoundCacheExpr = 5 * time.Second
+
+// APIKeys represents API keys loaded from config.
+type APIKeys struct {
+	keys map[string]ghsearch.APIKey
+}
+
+// APIKey returns API key details, ErrAPIKeyNotFound when its unknown.
+func (s *APIKeys) APIKey(_ context.Context, key string) (*ghsearch.APIKey, error) {
+	k, ok := s.keys[key]
+	if !ok {
+		return nil, ghsearch.ErrAPIKeyNotFound
+	}
+	return &k, nil
+}
+
+// NewAPIKeys creates API key store from keys mapped by the key itself.
+func NewAPIKeys(keys map[string]ghsearch.APIKey) *APIKeys {
+	return &APIKeys{keys}
+}
+
+// APIKeyStoreCache represents API key store cache in memory that saves a
+// store round trip on every request. Unknown keys are cached separately so
+// guessed keys cannot evict known ones.
+type APIKeyStoreCache struct {
+	cache        *Cache
+	notFound     *Cache
+	store        ghsearch.APIKeyStore
+	expr         time.Duration
+	notFoundExpr time.Duration
+}
+
+// APIKey returns API key details from cache or the store when expired.
+func (c *APIKeyStoreCache) APIKey(ctx context.Context, key string) (*ghsearch.APIKey, error) {
+	// Cached by its hash to keep the entry size fixed regardless of the key given.
+	sum := sha256.Sum256([]byte(key))
+	ck := string(sum[:])
+	if v, ok := c.cache.Get(ck); ok {
+		k := v.(ghsearch.APIKey)
+		return &k, nil
+	}
+	if c.notFound != nil {
+		if _, ok := c.notFound.Get(ck); ok {
+			return nil, ghsearch.ErrAPIKeyNotFound
+		}
+	}
+
+	k, err := c.store.APIKey(ctx, key)
+	if errors.Is(err, ghsearch.ErrAPIKeyNotFound) {
+		if c.notFound != nil {
+			c.notFound.Set(ck, struct{}{}, c.notFoundExpr)
+		}
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	c.cache.Set(ck, *k, c.expr)
+	return k, nil
+}
+
+// SetExpr sets how long API keys are cached.
+func (c *APIKeyStoreCache) SetExpr(d time.Duration) {
+	c.expr = d
+}
+
+// SetNotFoundExpr sets how long unknown API keys are cached.
+func (c *APIKeyStoreCache) SetNotFoundExpr(d time.Duration) {
+	c.notFoundExpr = d
+}
+
+// NewAPIKeyStore creates API key store cache in front of store, unknown
+// keys are cached on notFound and not cached at all when its nil.
+func NewAPIKeyStore(c, notFound *Cache, store ghsearch.APIKeyStore) *APIKeyStoreCache {
+	return &APIKeyStoreCache{
+		cache:        c,
+		notFound:     notFound,
+		store:        store,
+		expr:         DefaultAPIKeyCacheExpr,
+		notFoundExpr: DefaultAPIKeyNotFoundCacheExpr,
+	}
+}
@@ -0,0 +1,125 @@
+package memory_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/kudarap/ghsearch"
+	"github.com/kudarap/ghsearch/memory"
+)
+
+func TestAPIKeyStoreCache_APIKey(t *testing.T) {
+	store := &countingAPIKeyStore{keys: map[string]ghsearch.APIKey{
+		"secret": {ID: "frontend", Scopes: []string{"users"}},
+	}}
+	c := memory.NewAPIKeyStore(memory.NewCache(10), memory.NewCache(1), store)
+	c.SetExpr(50 * time.Millisecond)
+	ctx := context.Background()
+
+	testcases := []struct {
+		name string
+		// args
+		key string
+		// returns
+		want      *ghsearch.APIKey
+		wantErr   error
+		wantCalls int
+	}{
+		{"known key from store", "secret", &ghsearch.APIKey{ID: "frontend", Scopes: []string{"users"}}, nil, 1},
+		{"known key from cache", "secret", &ghsearch.APIKey{ID: "frontend", Scopes: []string{"users"}}, nil, 1},
+		{"unknown key from store", "guess", nil, ghsearch.ErrAPIKeyNotFound, 2},
+		{"unknown key from cache", "guess", nil, ghsearch.ErrAPIKeyNotFound, 2},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := c.APIKey(ctx, tc.key)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("err: %#v, want: %#v", err, tc.wantErr)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("\ngot: \n\t%#v \nwant: \n\t%#v", got, tc.want)
+			}
+			if store.calls != tc.wantCalls {
+				t.Errorf("calls: %d, want: %d", store.calls, tc.wantCalls)
+			}
+		})
+	}
+
+	// Unknown keys evict each other but not known keys.
+	c.APIKey(ctx, "guess-2")
+	c.APIKey(ctx, "guess")
+	c.APIKey(ctx, "secret")
+	if want := 4; store.calls != want {
+		t.Errorf("calls: %d, want: %d", store.calls, want)
+	}
+
+	// Removed key is rejected once expired.
+	delete(store.keys, "secret")
+	time.Sleep(60 * time.Millisecond)
+	if _, err := c.APIKey(ctx, "secret"); !errors.Is(err, ghsearch.ErrAPIKeyNotFound) {
+		t.Errorf("err: %#v, want: %#v", err, ghsearch.ErrAPIKeyNotFound)
+	}
+
+	// Store failure is not cached.
+	store.err = errors.New("store down")
+	calls := store.calls
+	c.APIKey(ctx, "other")
+	store.err = nil
+	c.APIKey(ctx, "other")
+	if want := calls + 2; store.calls != want {
+		t.Errorf("calls: %d, want: %d", store.calls, want)
+	}
+}
+
+func TestAPIKeyStoreCache_APIKeyNotFound(t *testing.T) {
+	testcases := []struct {
+		name string
+		// deps
+		notFound *memory.Cache
+		sleep    time.Duration
+		// returns
+		wantCalls int
+	}{
+		{"cached", memory.NewCache(10), 0, 1},
+		{"expired", memory.NewCache(10), 30 * time.Millisecond, 2},
+		{"not cached", nil, 0, 2},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			store := &countingAPIKeyStore{keys: map[string]ghsearch.APIKey{}}
+			c := memory.NewAPIKeyStore(memory.NewCache(10), tc.notFound, store)
+			c.SetNotFoundExpr(20 * time.Millisecond)
+			ctx := context.Background()
+
+			c.APIKey(ctx, "guess")
+			time.Sleep(tc.sleep)
+			if _, err := c.APIKey(ctx, "guess"); !errors.Is(err, ghsearch.ErrAPIKeyNotFound) {
+				t.Errorf("err: %#v, want: %#v", err, ghsearch.ErrAPIKeyNotFound)
+			}
+			if store.calls != tc.wantCalls {
+				t.Errorf("calls: %d, want: %d", store.calls, tc.wantCalls)
+			}
+		})
+	}
+}
+
+type countingAPIKeyStore struct {
+	keys  map[string]ghsearch.APIKey
+	err   error
+	calls int
+}
+
+func (s *countingAPIKeyStore) APIKey(_ context.Context, key string) (*ghsearch.APIKey, error) {
+	s.calls++
+	if s.err != nil {
+		return nil, s.err
+	}
+	k, ok := s.keys[key]
+	if !ok {
+		return nil, ghsearch.ErrAPIKeyNotFound
+	}
+	return &k, nil
+}
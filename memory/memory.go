@@ -0,0 +1,103 @@
+package memory
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// DefaultCapacity represents default maximum number of cached items.
+const DefaultCapacity = 1000
+
+// Cache represents a size-bounded in-memory LRU cache with expiring items.
+type Cache struct {
+	capacity int
+
+	mu    sync.Mutex
+	ll    *list.List
+	items map[string]*list.Element
+}
+
+type item struct {
+	key       string
+	val       interface{}
+	expiresAt time.Time
+}
+
+// Get returns cached value of key when its not expired.
+func (c *Cache) Get(key string) (val interface{}, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	it := el.Value.(*item)
+	if !it.expiresAt.IsZero() && time.Now().After(it.expiresAt) {
+		c.remove(el)
+		return nil, false
+	}
+
+	c.ll.MoveToFront(el)
+	return it.val, true
+}
+
+// Set caches value of key that expires after expr, zero expr never expires.
+// Least recently used item is evicted when capacity is reached.
+func (c *Cache) Set(key string, val interface{}, expr time.Duration) {
+	var expiresAt time.Time
+	if expr > 0 {
+		expiresAt = time.Now().Add(expr)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[key]; ok {
+		it := el.Value.(*item)
+		it.val, it.expiresAt = val, expiresAt
+		c.ll.MoveToFront(el)
+		return
+	}
+
+	c.items[key] = c.ll.PushFront(&item{key, val, expiresAt})
+	if c.ll.Len() > c.capacity {
+		c.remove(c.ll.Back())
+	}
+}
+
+// Delete removes cached value of key.
+func (c *Cache) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[key]; ok {
+		c.remove(el)
+	}
+}
+
+// Len returns number of cached items including expired ones not yet evicted.
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len()
+}
+
+func (c *Cache) remove(el *list.Element) {
+	c.ll.Remove(el)
+	delete(c.items, el.Value.(*item).key)
+}
+
+// NewCache returns a new in-memory cache that holds up to capacity items.
+func NewCache(capacity int) *Cache {
+	if capacity <= 0 {
+		capacity = DefaultCapacity
+	}
+
+	return &Cache{
+		capacity: capacity,
+		ll:       list.New(),
+		items:    map[string]*list.Element{},
+	}
+}
@@ -0,0 +1,52 @@
+package memory_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kudarap/ghsearch/memory"
+)
+
+func TestCache_LRU(t *testing.T) {
+	c := memory.NewCache(2)
+	c.Set("kudarap", 1, 0)
+	c.Set("spec", 2, 0)
+
+	// Recently used should survive the eviction.
+	if _, ok := c.Get("kudarap"); !ok {
+		t.Fatal("kudarap should be cached")
+	}
+	c.Set("dazz", 3, 0)
+
+	if _, ok := c.Get("spec"); ok {
+		t.Error("spec should be evicted")
+	}
+	for _, key := range []string{"kudarap", "dazz"} {
+		if _, ok := c.Get(key); !ok {
+			t.Errorf("%s should be cached", key)
+		}
+	}
+	if c.Len() != 2 {
+		t.Errorf("len: %d, want: 2", c.Len())
+	}
+}
+
+func TestCache_Expiry(t *testing.T) {
+	c := memory.NewCache(10)
+	c.Set("kudarap", 1, 50*time.Millisecond)
+	c.Set("spec", 2, 0)
+
+	if v, ok := c.Get("kudarap"); !ok || v != 1 {
+		t.Fatalf("got: %v %v, want: 1 true", v, ok)
+	}
+	time.Sleep(60 * time.Millisecond)
+	if _, ok := c.Get("kudarap"); ok {
+		t.Error("kudarap should be expired")
+	}
+	if _, ok := c.Get("spec"); !ok {
+		t.Error("spec should never expire")
+	}
+	if c.Len() != 1 {
+		t.Errorf("len: %d, want: 1", c.Len())
+	}
+}
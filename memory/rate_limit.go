@@ -0,0 +1,53 @@
+package memory
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// RateLimiter represents in-memory token buckets per key, limits only hold
+// within a single instance.
+type RateLimiter struct {
+	mu      sync.Mutex
+	buckets *Cache
+}
+
+type bucket struct {
+	tokens    float64
+	updatedAt time.Time
+}
+
+// TakeToken takes a token from key bucket that holds up to burst tokens
+// and refills at rate tokens per second.
+func (l *RateLimiter) TakeToken(_ context.Context, key string, rate float64, burst int) (ok bool, remaining int, err error) {
+	now := time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b := bucket{tokens: float64(burst)}
+	if v, found := l.buckets.Get(key); found {
+		b = v.(bucket)
+		b.tokens = math.Min(float64(burst), b.tokens+now.Sub(b.updatedAt).Seconds()*rate)
+	}
+	b.updatedAt = now
+	if ok = b.tokens >= 1; ok {
+		b.tokens--
+	}
+
+	// Bucket is dropped once refilled since a new one starts full.
+	refill := time.Duration((float64(burst) - b.tokens) / rate * float64(time.Second))
+	if refill <= 0 {
+		l.buckets.Delete(key)
+	} else {
+		l.buckets.Set(key, b, refill)
+	}
+	return ok, int(b.tokens), nil
+}
+
+// NewRateLimiter creates in-memory rate limiter that tracks up to capacity
+// keys, least recently used key is evicted and starts with a full bucket.
+func NewRateLimiter(capacity int) *RateLimiter {
+	return &RateLimiter{buckets: NewCache(capacity)}
+}
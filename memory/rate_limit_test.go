@@ -0,0 +1,35 @@
+package memory_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kudarap/ghsearch/memory"
+)
+
+func TestRateLimiter_TakeToken(t *testing.T) {
+	l := memory.NewRateLimiter(10)
+	ctx := context.Background()
+
+	wantRemaining := []int{1, 0, 0}
+	for i, want := range wantRemaining {
+		ok, remaining, err := l.TakeToken(ctx, "ip:127.0.0.1", 20, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if wantOK := i < 2; ok != wantOK || remaining != want {
+			t.Errorf("take %d got: %v %d, want: %v %d", i, ok, remaining, wantOK, want)
+		}
+	}
+
+	// Other keys have their own bucket.
+	if ok, _, _ := l.TakeToken(ctx, "ip:127.0.0.2", 20, 2); !ok {
+		t.Error("other key should be allowed")
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if ok, _, _ := l.TakeToken(ctx, "ip:127.0.0.1", 20, 2); !ok {
+		t.Error("should be allowed after refill")
+	}
+}
@@ -0,0 +1,189 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/kudarap/ghsearch"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// DefaultUserCacheExpr sets how long users are cached in memory.
+	DefaultUserCacheExpr = ghsearch.DefaultCacheFreshness
+
+	// DefaultNotFoundCacheExpr sets how long not found users are cached in memory.
+	DefaultNotFoundCacheExpr = 30 * time.Second
+)
+
+// UserSourceCache represents user source cache in memory. It can be used on
+// its own for single instance deployments or in front of a shared cache
+// to save a round trip for frequently requested users.
+type UserSourceCache struct {
+	cache   *Cache
+	userSrc ghsearch.UserSource
+
+	userExpr     time.Duration
+	notFoundExpr time.Duration
+
+	// observer receives cache events for instrumentation, disabled when nil.
+	observer Observer
+}
+
+// Observer receives cache events for instrumentation.
+type Observer interface {
+	// CacheResult reports a lookup result of cache name.
+	CacheResult(cache, result string)
+}
+
+// tracer returns tracer of the global tracer provider at the time of use
+// so a replaced provider takes effect, spans are not recorded until its set.
+func tracer() trace.Tracer {
+	return otel.Tracer("github.com/kudarap/ghsearch/memory")
+}
+
+// Cache lookup results for instrumentation.
+const (
+	cacheResultHit    = "hit"
+	cacheResultMiss   = "miss"
+	cacheResultBypass = "bypass"
+)
+
+// cachedUser represents cached user or a tombstone when user is nil.
+type cachedUser struct {
+	user     *ghsearch.User
+	cachedAt time.Time
+}
+
+// User returns user details from cache when available.
+func (c *UserSourceCache) User(ctx context.Context, username string) (*ghsearch.User, error) {
+	ctx, span := tracer().Start(ctx, "memory.UserSourceCache.User")
+	defer span.End()
+
+	if r, ok := c.cachedResult(ctx, username); ok {
+		return r.User, r.Err
+	}
+
+	user, err := c.userSrc.User(ctx, username)
+	c.store(ctx, username, user, err)
+	return user, err
+}
+
+// Users returns users details from cache when available,
+// only the misses are looked up from the source at once.
+func (c *UserSourceCache) Users(ctx context.Context, usernames []string) ([]ghsearch.UserResult, error) {
+	ctx, span := tracer().Start(ctx, "memory.UserSourceCache.Users", trace.WithAttributes(
+		attribute.Int("cache.usernames.count", len(usernames)),
+	))
+	defer span.End()
+
+	results := make([]ghsearch.UserResult, len(usernames))
+	var misses []int
+	var missUsernames []string
+	for i, uname := range usernames {
+		if r, ok := c.cachedResult(ctx, uname); ok {
+			results[i] = r
+			continue
+		}
+		misses = append(misses, i)
+		missUsernames = append(missUsernames, uname)
+	}
+	if len(misses) == 0 {
+		return results, nil
+	}
+
+	found := ghsearch.SourceUsers(ctx, c.userSrc, missUsernames)
+	for j, i := range misses {
+		r := found[j]
+		c.store(ctx, r.Username, r.User, r.Err)
+		results[i] = r
+	}
+	return results, nil
+}
+
+// cachedResult resolves a lookup result from cache, ok is false when
+// its not cached or too old for caller cache policy.
+func (c *UserSourceCache) cachedResult(ctx context.Context, username string) (r ghsearch.UserResult, ok bool) {
+	policy := ghsearch.CachePolicyFrom(ctx)
+	if policy.NoCache {
+		c.observe(ctx, username, cacheResultBypass)
+		return r, false
+	}
+	v, ok := c.cache.Get(username)
+	if !ok {
+		c.observe(ctx, username, cacheResultMiss)
+		return r, false
+	}
+	cached := v.(cachedUser)
+	if policy.MaxAge > 0 && time.Since(cached.cachedAt) >= policy.MaxAge {
+		c.observe(ctx, username, cacheResultMiss)
+		return r, false
+	}
+	c.observe(ctx, username, cacheResultHit)
+
+	r.Username = username
+	if cached.user == nil {
+		r.Err = ghsearch.ErrUserNotFound
+		return r, true
+	}
+	r.User = copyUser(cached.user)
+	return r, true
+}
+
+// observe reports cache lookup result to observer and current span.
+func (c *UserSourceCache) observe(ctx context.Context, username, result string) {
+	trace.SpanFromContext(ctx).AddEvent("cache lookup", trace.WithAttributes(
+		attribute.String("cache.username", username),
+		attribute.String("cache.result", result),
+	))
+	if c.observer != nil {
+		c.observer.CacheResult("memory", result)
+	}
+}
+
+// store caches a source lookup result.
+func (c *UserSourceCache) store(ctx context.Context, username string, user *ghsearch.User, err error) {
+	if errors.Is(err, ghsearch.ErrUserNotFound) && c.notFoundExpr > 0 {
+		c.cache.Set(username, cachedUser{cachedAt: time.Now()}, c.notFoundExpr)
+		return
+	}
+	if err != nil || user == nil {
+		return
+	}
+	// Stale user from the next tier should be revalidated there,
+	// caching here will hide it from being refreshed.
+	if !ghsearch.IsStale(ctx, username) {
+		c.cache.Set(username, cachedUser{copyUser(user), time.Now()}, c.userExpr)
+	}
+}
+
+// SetObserver enables cache instrumentation.
+func (c *UserSourceCache) SetObserver(o Observer) {
+	c.observer = o
+}
+
+// SetExpr sets how long users and not found users are cached,
+// zero not found expiry disables caching not found users.
+func (c *UserSourceCache) SetExpr(user, notFound time.Duration) {
+	c.userExpr = user
+	c.notFoundExpr = notFound
+}
+
+// copyUser prevents callers from modifying cached user.
+func copyUser(u *ghsearch.User) *ghsearch.User {
+	cp := *u
+	return &cp
+}
+
+// NewUserSource creates new instance of user source with in-memory cache.
+func NewUserSource(c *Cache, us ghsearch.UserSource) *UserSourceCache {
+	return &UserSourceCache{
+		cache:        c,
+		userSrc:      us,
+		userExpr:     DefaultUserCacheExpr,
+		notFoundExpr: DefaultNotFoundCacheExpr,
+	}
+}
@@ -0,0 +1,161 @@
+package memory_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/kudarap/ghsearch"
+	"github.com/kudarap/ghsearch/memory"
+)
+
+func TestUserSourceCache_User(t *testing.T) {
+	testcases := []struct {
+		name string
+		// deps
+		source *countingUserSource
+		// returns
+		want      *ghsearch.User
+		wantErr   error
+		wantCalls int
+	}{
+		{
+			"cached",
+			&countingUserSource{user: &ghsearch.User{Login: "kudarap"}},
+			&ghsearch.User{Login: "kudarap"},
+			nil,
+			1,
+		},
+		{
+			"not found cached",
+			&countingUserSource{err: ghsearch.ErrUserNotFound},
+			nil,
+			ghsearch.ErrUserNotFound,
+			1,
+		},
+		{
+			"error not cached",
+			&countingUserSource{err: ghsearch.ErrUserSourceTimeout},
+			nil,
+			ghsearch.ErrUserSourceTimeout,
+			2,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			uc := memory.NewUserSource(memory.NewCache(10), tc.source)
+
+			var got *ghsearch.User
+			var gotErr error
+			for i := 0; i < 2; i++ {
+				got, gotErr = uc.User(ctx, "kudarap")
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("\ngot: \n\t%#v \nwant: \n\t%#v", got, tc.want)
+			}
+			if !errors.Is(gotErr, tc.wantErr) {
+				t.Errorf("err: %#v, want: %#v", gotErr, tc.wantErr)
+			}
+			if tc.source.calls != tc.wantCalls {
+				t.Errorf("calls: %d, want: %d", tc.source.calls, tc.wantCalls)
+			}
+		})
+	}
+}
+
+func TestUserSourceCache_Users(t *testing.T) {
+	ctx := context.Background()
+	source := &countingUserSource{user: &ghsearch.User{Login: "kudarap"}}
+	uc := memory.NewUserSource(memory.NewCache(10), source)
+	if _, err := uc.User(ctx, "kudarap"); err != nil {
+		t.Fatalf("err: %#v, want: nil", err)
+	}
+
+	// Only the miss is looked up from the source.
+	got, err := uc.Users(ctx, []string{"kudarap", "dazz"})
+	if err != nil {
+		t.Fatalf("err: %#v, want: nil", err)
+	}
+	want := []ghsearch.UserResult{
+		{Username: "kudarap", User: &ghsearch.User{Login: "kudarap"}},
+		{Username: "dazz", User: &ghsearch.User{Login: "kudarap"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("\ngot: \n\t%#v \nwant: \n\t%#v", got, want)
+	}
+	if source.calls != 2 {
+		t.Errorf("calls: %d, want: %d", source.calls, 2)
+	}
+}
+
+func TestUserSourceCache_Users_Batch(t *testing.T) {
+	ctx := context.Background()
+	source := &batchUserSource{}
+	// Same decoration order as serverd, batch path is kept through each layer.
+	cb := ghsearch.NewCircuitBreaker(source, ghsearch.DefaultCircuitBreakerConfig)
+	uc := memory.NewUserSource(memory.NewCache(10), cb)
+	if _, err := uc.User(ctx, "kudarap"); err != nil {
+		t.Fatalf("err: %#v, want: nil", err)
+	}
+
+	if _, err := uc.Users(ctx, []string{"kudarap", "dazz", "spec"}); err != nil {
+		t.Fatalf("err: %#v, want: nil", err)
+	}
+	want := [][]string{{"kudarap"}, {"dazz", "spec"}}
+	if !reflect.DeepEqual(source.batches, want) {
+		t.Errorf("\ngot: \n\t%#v \nwant: \n\t%#v", source.batches, want)
+	}
+}
+
+func TestUserSourceCache_User_CachePolicy(t *testing.T) {
+	source := &countingUserSource{user: &ghsearch.User{Login: "kudarap"}}
+	uc := memory.NewUserSource(memory.NewCache(10), source)
+	uc.User(context.Background(), "kudarap")
+
+	ctx := ghsearch.WithCachePolicy(context.Background(), ghsearch.CachePolicy{MaxAge: time.Minute})
+	uc.User(ctx, "kudarap")
+	if source.calls != 1 {
+		t.Errorf("calls: %d, want: %d", source.calls, 1)
+	}
+
+	ctx = ghsearch.WithCachePolicy(context.Background(), ghsearch.CachePolicy{NoCache: true})
+	uc.User(ctx, "kudarap")
+	if source.calls != 2 {
+		t.Errorf("calls: %d, want: %d", source.calls, 2)
+	}
+}
+
+type countingUserSource struct {
+	user  *ghsearch.User
+	err   error
+	calls int
+}
+
+func (s *countingUserSource) User(ctx context.Context, username string) (*ghsearch.User, error) {
+	s.calls++
+	return s.user, s.err
+}
+
+type batchUserSource struct {
+	batches [][]string
+}
+
+func (s *batchUserSource) User(ctx context.Context, username string) (*ghsearch.User, error) {
+	rr, err := s.Users(ctx, []string{username})
+	if err != nil {
+		return nil, err
+	}
+	return rr[0].User, rr[0].Err
+}
+
+func (s *batchUserSource) Users(_ context.Context, usernames []string) ([]ghsearch.UserResult, error) {
+	s.batches = append(s.batches, usernames)
+	results := make([]ghsearch.UserResult, len(usernames))
+	for i, uname := range usernames {
+		results[i] = ghsearch.UserResult{Username: uname, User: &ghsearch.User{Login: uname}}
+	}
+	return results, nil
+}
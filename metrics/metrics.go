@@ -0,0 +1,183 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "ghsearch"
+
+// Metrics represents Prometheus metrics of the service. It satisfies the
+// instrumentation interfaces of http, redis, memory and github packages
+// without them depending on Prometheus.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	httpRequests   *prometheus.CounterVec
+	httpDuration   *prometheus.HistogramVec
+	cacheResults   *prometheus.CounterVec
+	githubRequests *prometheus.CounterVec
+	dedups         *prometheus.CounterVec
+	apiKeyRequests *prometheus.CounterVec
+
+	mu         sync.Mutex
+	rateLimits []func() []RateLimit
+}
+
+// RateLimit represents a rate limit reading of a GitHub API resource.
+type RateLimit struct {
+	Resource  string
+	Token     string
+	Limit     int
+	Remaining int
+	ResetsAt  time.Time
+}
+
+// ObserveRequest records a handled http request.
+func (m *Metrics) ObserveRequest(route, method string, status int, d time.Duration) {
+	code := strconv.Itoa(status)
+	m.httpRequests.WithLabelValues(route, method, code).Inc()
+	m.httpDuration.WithLabelValues(route, method, code).Observe(d.Seconds())
+}
+
+// APIKeyRequest records a handled http request of an API key.
+func (m *Metrics) APIKeyRequest(key, route string, status int) {
+	m.apiKeyRequests.WithLabelValues(key, route, strconv.Itoa(status)).Inc()
+}
+
+// CacheResult records a cache lookup result, either hit, stale or miss.
+func (m *Metrics) CacheResult(cache, result string) {
+	m.cacheResults.WithLabelValues(cache, result).Inc()
+}
+
+// GithubRequest records a GitHub API call, zero status indicates
+// the call failed without a response.
+func (m *Metrics) GithubRequest(api string, status int) {
+	code := "error"
+	if status > 0 {
+		code = strconv.Itoa(status)
+	}
+	m.githubRequests.WithLabelValues(api, code).Inc()
+}
+
+// RequestDeduped records a request that shared the result of
+// an in-flight duplicate request.
+func (m *Metrics) RequestDeduped(group string) {
+	m.dedups.WithLabelValues(group).Inc()
+}
+
+// AddRateLimits registers fn that is read on each scrape for rate limit gauges.
+func (m *Metrics) AddRateLimits(fn func() []RateLimit) {
+	m.mu.Lock()
+	m.rateLimits = append(m.rateLimits, fn)
+	m.mu.Unlock()
+}
+
+// Handler returns http handler that exposes metrics.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// New creates new metrics with Go runtime and process metrics.
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "Number of handled http requests.",
+		}, []string{"route", "method", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Latency of handled http requests.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "method", "status"}),
+		cacheResults: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "cache_results_total",
+			Help:      "Number of user cache lookups by result.",
+		}, []string{"cache", "result"}),
+		githubRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "github_requests_total",
+			Help:      "Number of GitHub API calls by response status.",
+		}, []string{"api", "status"}),
+		dedups: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "deduped_requests_total",
+			Help:      "Number of requests that shared an in-flight duplicate request.",
+		}, []string{"group"}),
+		apiKeyRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "api_key_requests_total",
+			Help:      "Number of handled http requests by API key.",
+		}, []string{"key", "route", "status"}),
+	}
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests,
+		m.httpDuration,
+		m.cacheResults,
+		m.githubRequests,
+		m.dedups,
+		m.apiKeyRequests,
+		&rateLimitCollector{m},
+	)
+	return m
+}
+
+var (
+	rateLimitLimitDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "github", "rate_limit_limit"),
+		"GitHub API rate limit per reset window.",
+		[]string{"resource", "token"}, nil,
+	)
+	rateLimitRemainingDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "github", "rate_limit_remaining"),
+		"GitHub API rate limit remaining until reset.",
+		[]string{"resource", "token"}, nil,
+	)
+	rateLimitResetDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "github", "rate_limit_reset_seconds"),
+		"Seconds until GitHub API rate limit resets.",
+		[]string{"resource", "token"}, nil,
+	)
+)
+
+// rateLimitCollector reads rate limits on scrape so gauges are never outdated.
+type rateLimitCollector struct {
+	m *Metrics
+}
+
+func (c *rateLimitCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- rateLimitLimitDesc
+	ch <- rateLimitRemainingDesc
+	ch <- rateLimitResetDesc
+}
+
+func (c *rateLimitCollector) Collect(ch chan<- prometheus.Metric) {
+	c.m.mu.Lock()
+	fns := c.m.rateLimits
+	c.m.mu.Unlock()
+
+	now := time.Now()
+	for _, fn := range fns {
+		for _, rl := range fn() {
+			reset := rl.ResetsAt.Sub(now).Seconds()
+			if rl.ResetsAt.IsZero() || reset < 0 {
+				reset = 0
+			}
+			ch <- prometheus.MustNewConstMetric(rateLimitLimitDesc, prometheus.GaugeValue, float64(rl.Limit), rl.Resource, rl.Token)
+			ch <- prometheus.MustNewConstMetric(rateLimitRemainingDesc, prometheus.GaugeValue, float64(rl.Remaining), rl.Resource, rl.Token)
+			ch <- prometheus.MustNewConstMetric(rateLimitResetDesc, prometheus.GaugeValue, reset, rl.Resource, rl.Token)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package metrics_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kudarap/ghsearch/metrics"
+)
+
+func TestMetrics_Handler(t *testing.T) {
+	m := metrics.New()
+	m.ObserveRequest("/users", http.MethodGet, http.StatusOK, 10*time.Millisecond)
+	m.APIKeyRequest("ci", "/users", http.StatusTooManyRequests)
+	m.CacheResult("redis", "hit")
+	m.CacheResult("redis", "hit")
+	m.GithubRequest("rest", 0)
+	m.RequestDeduped("github")
+	m.AddRateLimits(func() []metrics.RateLimit {
+		return []metrics.RateLimit{{Resource: "core", Token: "0", Limit: 5000, Remaining: 42}}
+	})
+
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	b, _ := ioutil.ReadAll(w.Body)
+	got := string(b)
+
+	wants := []string{
+		`ghsearch_http_requests_total{method="GET",route="/users",status="200"} 1`,
+		`ghsearch_http_request_duration_seconds_count{method="GET",route="/users",status="200"} 1`,
+		`ghsearch_api_key_requests_total{key="ci",route="/users",status="429"} 1`,
+		`ghsearch_cache_results_total{cache="redis",result="hit"} 2`,
+		`ghsearch_github_requests_total{api="rest",status="error"} 1`,
+		`ghsearch_deduped_requests_total{group="github"} 1`,
+		`ghsearch_github_rate_limit_remaining{resource="core",token="0"} 42`,
+		`ghsearch_github_rate_limit_reset_seconds{resource="core",token="0"} 0`,
+	}
+	for _, want := range wants {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing metric: %s", want)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package redis
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+
+	"github.com/kudarap/ghsearch"
+)
+
+const apiKeyKeyPrefix = "apikey:"
+
+// APIKeys represents API keys stored on redis as JSON under
+// apikey:<sha256 hex of the key> so stored keys can't be used when leaked.
+type APIKeys struct {
+	cache *Client
+}
+
+// APIKey returns API key details, ErrAPIKeyNotFound when its unknown.
+func (s *APIKeys) APIKey(ctx context.Context, key string) (*ghsearch.APIKey, error) {
+	sum := sha256.Sum256([]byte(key))
+	k := &ghsearch.APIKey{}
+	ok, err := s.cache.Get(ctx, apiKeyKeyPrefix+hex.EncodeToString(sum[:]), k)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ghsearch.ErrAPIKeyNotFound
+	}
+	return k, nil
+}
+
+// NewAPIKeys creates API key store with redis.
+func NewAPIKeys(c *Client) *APIKeys {
+	return &APIKeys{c}
+}
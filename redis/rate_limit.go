@@ -0,0 +1,137 @@
+package redis
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	rateLimitKeyPrefix = "ratelimit:"
+
+	// rateLimitExprGrace keeps budget a little longer after reset, stale
+	// budget is still treated as reset by the scripts.
+	rateLimitExprGrace = time.Minute
+)
+
+// consumeRateLimitScript decrements remaining when available and its
+// still within the rate limit window. Window is checked with redis clock
+// so instances with clock skew agree when the shared budget resets.
+//
+// Returns {allowed, resets_at}, unknown or reset budget is allowed with zero resets_at.
+var consumeRateLimitScript = redis.NewScript(`
+if redis.replicate_commands then
+	redis.replicate_commands()
+end
+local now = tonumber(redis.call('TIME')[1])
+local remaining = tonumber(redis.call('HGET', KEYS[1], 'remaining'))
+local reset = tonumber(redis.call('HGET', KEYS[1], 'reset'))
+if remaining == nil or reset == nil or reset <= now then
+	return {1, 0}
+end
+if remaining <= 0 then
+	return {0, reset}
+end
+redis.call('HINCRBY', KEYS[1], 'remaining', -1)
+return {1, reset}
+`)
+
+// updateRateLimitScript replaces the budget when its from a newer window
+// or has lower remaining since responses may arrive out of order.
+var updateRateLimitScript = redis.NewScript(`
+local limit = tonumber(ARGV[1])
+local remaining = tonumber(ARGV[2])
+local reset = tonumber(ARGV[3])
+local cur_remaining = tonumber(redis.call('HGET', KEYS[1], 'remaining'))
+local cur_reset = tonumber(redis.call('HGET', KEYS[1], 'reset'))
+if cur_reset == nil or reset > cur_reset or remaining < cur_remaining then
+	redis.call('HSET', KEYS[1], 'limit', limit, 'remaining', remaining, 'reset', reset)
+	redis.call('EXPIREAT', KEYS[1], reset + tonumber(ARGV[4]))
+end
+return 1
+`)
+
+// releaseRateLimitScript gives back a request when the budget still exists.
+var releaseRateLimitScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 1 then
+	redis.call('HINCRBY', KEYS[1], 'remaining', 1)
+end
+return 1
+`)
+
+// ConsumeRateLimit atomically takes a request from shared rate limit budget.
+func (c *Client) ConsumeRateLimit(ctx context.Context, key string) (ok bool, resetsAt time.Time, err error) {
+	keys := []string{keyPrefix + rateLimitKeyPrefix + key}
+	res, err := consumeRateLimitScript.Run(ctx, c.db, keys).Int64Slice()
+	if err != nil {
+		return false, time.Time{}, err
+	}
+
+	if res[1] != 0 {
+		resetsAt = time.Unix(res[1], 0)
+	}
+	return res[0] == 1, resetsAt, nil
+}
+
+// ReleaseRateLimit gives back a request to shared rate limit budget.
+func (c *Client) ReleaseRateLimit(ctx context.Context, key string) error {
+	keys := []string{keyPrefix + rateLimitKeyPrefix + key}
+	return releaseRateLimitScript.Run(ctx, c.db, keys).Err()
+}
+
+// UpdateRateLimit reconciles shared rate limit budget with the server rate limit.
+func (c *Client) UpdateRateLimit(ctx context.Context, key string, limit, remaining int, resetsAt time.Time) error {
+	// Skip unknown windows since it can't expire.
+	if resetsAt.IsZero() {
+		return nil
+	}
+
+	keys := []string{keyPrefix + rateLimitKeyPrefix + key}
+	grace := int64(rateLimitExprGrace / time.Second)
+	return updateRateLimitScript.Run(ctx, c.db, keys, limit, remaining, resetsAt.Unix(), grace).Err()
+}
+
+const clientRateLimitKeyPrefix = "clientlimit:"
+
+// takeTokenScript refills the bucket by elapsed time then takes a token
+// when available, bucket expires once its refilled since a new one starts full.
+// Elapsed time uses redis clock so instances with clock skew share the same bucket.
+// Older redis only allows writes after TIME with commands replication.
+//
+// Returns {allowed, remaining}.
+var takeTokenScript = redis.NewScript(`
+if redis.replicate_commands then
+	redis.replicate_commands()
+end
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local clock = redis.call('TIME')
+local now = tonumber(clock[1]) * 1000 + math.floor(tonumber(clock[2]) / 1000)
+local tokens = tonumber(redis.call('HGET', KEYS[1], 'tokens'))
+local ts = tonumber(redis.call('HGET', KEYS[1], 'ts'))
+if tokens == nil or ts == nil then
+	tokens = burst
+else
+	tokens = math.min(burst, tokens + math.max(0, now - ts) / 1000 * rate)
+end
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', now)
+redis.call('PEXPIRE', KEYS[1], math.ceil((burst - tokens) / rate * 1000) + 1)
+return {allowed, math.floor(tokens)}
+`)
+
+// TakeToken atomically takes a token from key bucket shared between instances,
+// bucket holds up to burst tokens and refills at rate tokens per second.
+func (c *Client) TakeToken(ctx context.Context, key string, rate float64, burst int) (ok bool, remaining int, err error) {
+	keys := []string{keyPrefix + clientRateLimitKeyPrefix + key}
+	res, err := takeTokenScript.Run(ctx, c.db, keys, rate, burst).Int64Slice()
+	if err != nil {
+		return false, 0, err
+	}
+	return res[0] == 1, int(res[1]), nil
+}
@@ -0,0 +1,161 @@
+package redis_test
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestClient_ConsumeRateLimit(t *testing.T) {
+	resetsAt := time.Now().Add(time.Hour).Truncate(time.Second)
+	testcases := []struct {
+		name string
+		// deps
+		remaining  int
+		resetsAt   time.Time
+		clockAhead time.Duration
+		// returns
+		wantOK       []bool
+		wantResetsAt time.Time
+	}{
+		{"unknown budget", -1, time.Time{}, 0, []bool{true, true}, time.Time{}},
+		{"consumes until exhausted", 2, resetsAt, 0, []bool{true, true, false}, resetsAt},
+		{"exhausted", 0, resetsAt, 0, []bool{false}, resetsAt},
+		{"reset budget", 0, time.Now().Add(-time.Second), 0, []bool{true}, time.Time{}},
+		{"reset by redis clock", 0, resetsAt, 2 * time.Hour, []bool{true}, time.Time{}},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			c, mr := newTestClient(t)
+			if tc.remaining >= 0 {
+				if err := c.UpdateRateLimit(ctx, "core:token", 5000, tc.remaining, tc.resetsAt); err != nil {
+					t.Fatalf("err: %#v, want: nil", err)
+				}
+			}
+			mr.SetTime(time.Now().Add(tc.clockAhead))
+
+			var gotResetsAt time.Time
+			for i, want := range tc.wantOK {
+				ok, r, err := c.ConsumeRateLimit(ctx, "core:token")
+				if err != nil {
+					t.Fatalf("err: %#v, want: nil", err)
+				}
+				if ok != want {
+					t.Errorf("consume %d: %t, want: %t", i, ok, want)
+				}
+				gotResetsAt = r
+			}
+			if !gotResetsAt.Equal(tc.wantResetsAt) {
+				t.Errorf("resets at: %s, want: %s", gotResetsAt, tc.wantResetsAt)
+			}
+		})
+	}
+}
+
+func TestClient_UpdateRateLimit(t *testing.T) {
+	resetsAt := time.Now().Add(time.Hour).Truncate(time.Second)
+	testcases := []struct {
+		name string
+		// args
+		remaining int
+		resetsAt  time.Time
+		// returns
+		wantRemaining string
+	}{
+		{"lower remaining", 5, resetsAt, "5"},
+		{"higher remaining of the same window", 20, resetsAt, "10"},
+		{"newer window", 4999, resetsAt.Add(time.Hour), "4999"},
+		{"older window", 4999, resetsAt.Add(-time.Hour), "10"},
+		{"unknown window", 1, time.Time{}, "10"},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			c, mr := newTestClient(t)
+			if err := c.UpdateRateLimit(ctx, "core:token", 5000, 10, resetsAt); err != nil {
+				t.Fatalf("err: %#v, want: nil", err)
+			}
+
+			if err := c.UpdateRateLimit(ctx, "core:token", 5000, tc.remaining, tc.resetsAt); err != nil {
+				t.Fatalf("err: %#v, want: nil", err)
+			}
+			if got := mr.HGet("gh-search-ratelimit:core:token", "remaining"); got != tc.wantRemaining {
+				t.Errorf("remaining: %s, want: %s", got, tc.wantRemaining)
+			}
+		})
+	}
+}
+
+func TestClient_UpdateRateLimit_Expiry(t *testing.T) {
+	c, mr := newTestClient(t)
+	resetsAt := time.Now().Add(time.Hour)
+	if err := c.UpdateRateLimit(context.Background(), "core:token", 5000, 10, resetsAt); err != nil {
+		t.Fatalf("err: %#v, want: nil", err)
+	}
+
+	// Budget outlives its window by a grace period.
+	ttl := mr.TTL("gh-search-ratelimit:core:token")
+	if ttl <= time.Hour || ttl > time.Hour+2*time.Minute {
+		t.Errorf("ttl: %s, want: about %s", ttl, time.Hour+time.Minute)
+	}
+}
+
+func TestClient_ReleaseRateLimit(t *testing.T) {
+	ctx := context.Background()
+	c, mr := newTestClient(t)
+
+	// Missing budget is not created since it would never expire.
+	if err := c.ReleaseRateLimit(ctx, "core:token"); err != nil {
+		t.Fatalf("err: %#v, want: nil", err)
+	}
+	if mr.Exists("gh-search-ratelimit:core:token") {
+		t.Error("released missing budget, want no budget")
+	}
+
+	c.UpdateRateLimit(ctx, "core:token", 5000, 0, time.Now().Add(time.Hour))
+	if err := c.ReleaseRateLimit(ctx, "core:token"); err != nil {
+		t.Fatalf("err: %#v, want: nil", err)
+	}
+	if ok, _, _ := c.ConsumeRateLimit(ctx, "core:token"); !ok {
+		t.Error("consume released request: false, want: true")
+	}
+}
+
+func TestClient_TakeToken(t *testing.T) {
+	ctx := context.Background()
+	c, mr := newTestClient(t)
+	now := time.Now()
+	mr.SetTime(now)
+
+	wantRemaining := []int{1, 0, 0}
+	for i, want := range wantRemaining {
+		ok, remaining, err := c.TakeToken(ctx, "ip:127.0.0.1", 1, 2)
+		if err != nil {
+			t.Fatalf("err: %#v, want: nil", err)
+		}
+		if wantOK := i < 2; ok != wantOK || remaining != want {
+			t.Errorf("take %d got: %v %d, want: %v %d", i, ok, remaining, wantOK, want)
+		}
+	}
+
+	// Other keys have their own bucket.
+	if ok, _, _ := c.TakeToken(ctx, "ip:127.0.0.2", 1, 2); !ok {
+		t.Error("other key should be allowed")
+	}
+
+	// Refills by redis clock regardless of instance clock.
+	mr.SetTime(now.Add(time.Second))
+	if ok, _, _ := c.TakeToken(ctx, "ip:127.0.0.1", 1, 2); !ok {
+		t.Error("should be allowed after refill")
+	}
+	if ok, _, _ := c.TakeToken(ctx, "ip:127.0.0.1", 1, 2); ok {
+		t.Error("should not be allowed before next refill")
+	}
+
+	// Bucket expires once its refilled.
+	ttl := mr.TTL("gh-search-clientlimit:ip:127.0.0.1")
+	if ttl <= time.Second || ttl > 2*time.Second+time.Millisecond {
+		t.Errorf("ttl: %s, want: ~%s", ttl, 2*time.Second)
+	}
+}
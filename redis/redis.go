@@ -10,6 +10,9 @@ import (
 
 const keyPrefix = "gh-search-"
 
+// scanCount hints how many keys are scanned per iteration.
+const scanCount = 100
+
 // Client represents Redis database client.
 type Client struct {
 	db *redis.Client
@@ -44,6 +47,132 @@ func (c *Client) Set(ctx context.Context, key string, val interface{}, expr time
 	return c.db.Set(ctx, keyPrefix+key, string(b), expr).Err()
 }
 
+// GetTTL gets value of key along with its remaining time to live.
+func (c *Client) GetTTL(ctx context.Context, key string, out interface{}) (ok bool, ttl time.Duration, err error) {
+	pipe := c.db.Pipeline()
+	get := pipe.Get(ctx, keyPrefix+key)
+	pttl := pipe.PTTL(ctx, keyPrefix+key)
+	if _, err = pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return false, 0, err
+	}
+
+	val, err := get.Result()
+	if err != nil {
+		if err == redis.Nil {
+			return false, 0, nil
+		}
+		return false, 0, err
+	}
+	if err = json.Unmarshal([]byte(val), out); err != nil {
+		return false, 0, err
+	}
+	return true, pttl.Val(), nil
+}
+
+// Delete removes keys and returns number of keys removed.
+func (c *Client) Delete(ctx context.Context, keys ...string) (int, error) {
+	if len(keys) == 0 {
+		return 0, nil
+	}
+
+	kk := make([]string, len(keys))
+	for i, k := range keys {
+		kk[i] = keyPrefix + k
+	}
+	n, err := c.db.Del(ctx, kk...).Result()
+	return int(n), err
+}
+
+// DeleteMatch removes keys matching glob-style pattern and returns number of
+// keys removed. Keys are scanned in batches to avoid blocking redis.
+func (c *Client) DeleteMatch(ctx context.Context, pattern string) (int, error) {
+	var deleted int
+	var cursor uint64
+	for {
+		keys, next, err := c.db.Scan(ctx, cursor, keyPrefix+pattern, scanCount).Result()
+		if err != nil {
+			return deleted, err
+		}
+		if len(keys) > 0 {
+			n, err := c.db.Del(ctx, keys...).Result()
+			if err != nil {
+				return deleted, err
+			}
+			deleted += int(n)
+		}
+		if cursor = next; cursor == 0 {
+			return deleted, nil
+		}
+	}
+}
+
+// GetMulti gets values of keys in a single round trip and decodes each hit
+// with newOut(i), returns whether each key was found in the same order.
+func (c *Client) GetMulti(ctx context.Context, keys []string, newOut func(i int) interface{}) ([]bool, error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	kk := make([]string, len(keys))
+	for i, k := range keys {
+		kk[i] = keyPrefix + k
+	}
+	vals, err := c.db.MGet(ctx, kk...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	hits := make([]bool, len(keys))
+	for i, v := range vals {
+		s, ok := v.(string)
+		if !ok {
+			continue
+		}
+		if err = json.Unmarshal([]byte(s), newOut(i)); err != nil {
+			return nil, err
+		}
+		hits[i] = true
+	}
+	return hits, nil
+}
+
+// Item represents a value to set with its own expiry.
+type Item struct {
+	Key  string
+	Val  interface{}
+	Expr time.Duration
+}
+
+// SetMulti sets items in a single pipeline.
+func (c *Client) SetMulti(ctx context.Context, items []Item) error {
+	pipe := c.db.Pipeline()
+	var queued bool
+	for _, it := range items {
+		// Skip caching when key and value is empty.
+		if it.Key == "" || it.Val == nil {
+			continue
+		}
+
+		b, err := json.Marshal(it.Val)
+		if err != nil {
+			return err
+		}
+		pipe.Set(ctx, keyPrefix+it.Key, string(b), it.Expr)
+		queued = true
+	}
+	if !queued {
+		return nil
+	}
+
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// Ping checks redis connection.
+func (c *Client) Ping(ctx context.Context) error {
+	return c.db.Ping(ctx).Err()
+}
+
 func (c *Client) Close() error {
 	return c.db.Close()
 }
@@ -0,0 +1,21 @@
+package redis_test
+
+import (
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/kudarap/ghsearch/redis"
+)
+
+// newTestClient returns client of in-memory redis server that runs
+// the same Lua scripts, server is closed once the test ends.
+func newTestClient(t *testing.T) (*redis.Client, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	c, err := redis.NewClient("redis://" + mr.Addr())
+	if err != nil {
+		t.Fatalf("err: %#v, want: nil", err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c, mr
+}
@@ -2,43 +2,361 @@ package redis
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"github.com/kudarap/ghsearch"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/sync/singleflight"
 )
 
-const userCacheExpr = time.Minute * 2
+const (
+	// userCacheExpr sets how long cached user is considered fresh.
+	userCacheExpr = ghsearch.DefaultCacheFreshness
+
+	// userCacheSoftExpr sets how long cached user is served immediately
+	// while being refreshed in the background.
+	userCacheSoftExpr = time.Minute * 10
+
+	// userCacheHardExpr sets how long cached user is kept to be served
+	// when the source fails.
+	userCacheHardExpr = time.Hour
+
+	// DefaultNotFoundCacheExpr sets how long not found users are cached.
+	DefaultNotFoundCacheExpr = 30 * time.Second
+
+	// refreshTimeout limits background refresh since its detached from the request.
+	refreshTimeout = 10 * time.Second
+
+	// userKeyPrefix namespaces cached user keys from other keys on the same redis.
+	userKeyPrefix = "user:"
+)
 
 // UserSourceCache represents user source cache with redis.
 type UserSourceCache struct {
-	cache   *Client
+	cache   Cache
 	userSrc ghsearch.UserSource
+
+	// notFoundExpr sets how long not found users are cached, zero disables it.
+	notFoundExpr time.Duration
+
+	// refreshGroup prevents duplicate background refresh of the same user.
+	refreshGroup singleflight.Group
+
+	// observer receives cache events for instrumentation, disabled when nil.
+	observer Observer
+}
+
+// Cache represents a key-value store of cached users, implemented by Client.
+type Cache interface {
+	Get(ctx context.Context, key string, out interface{}) (ok bool, err error)
+	GetMulti(ctx context.Context, keys []string, newOut func(i int) interface{}) ([]bool, error)
+	GetTTL(ctx context.Context, key string, out interface{}) (ok bool, ttl time.Duration, err error)
+	Set(ctx context.Context, key string, val interface{}, expr time.Duration) error
+	SetMulti(ctx context.Context, items []Item) error
+	Delete(ctx context.Context, keys ...string) (int, error)
+	DeleteMatch(ctx context.Context, pattern string) (int, error)
+}
+
+// Observer receives cache events for instrumentation.
+type Observer interface {
+	// CacheResult reports a lookup result of cache name.
+	CacheResult(cache, result string)
+
+	// RequestDeduped reports a request that shared the result of
+	// an in-flight duplicate request.
+	RequestDeduped(group string)
+}
+
+// tracer returns tracer of the global tracer provider at the time of use
+// so a replaced provider takes effect, spans are not recorded until its set.
+func tracer() trace.Tracer {
+	return otel.Tracer("github.com/kudarap/ghsearch/redis")
+}
+
+// Cache lookup results for instrumentation.
+const (
+	cacheResultHit    = "hit"
+	cacheResultStale  = "stale"
+	cacheResultMiss   = "miss"
+	cacheResultBypass = "bypass"
+)
+
+// cachedUser represents a cached user with its cache time.
+type cachedUser struct {
+	User     *ghsearch.User `json:"user"`
+	CachedAt time.Time      `json:"cached_at"`
+
+	// NotFound marks a tombstone of user that does not exist on the source.
+	NotFound bool `json:"not_found,omitempty"`
 }
 
 // User returns user details from cache when available.
+//
+// Cached user is served as-is while fresh, and also within soft expiry while
+// refreshing in the background. Past soft expiry, a new user value is required
+// but stale user is still served when the source fails. Soft expiry can be
+// overridden by caller cache policy.
 func (c *UserSourceCache) User(ctx context.Context, username string) (*ghsearch.User, error) {
+	ctx, span := tracer().Start(ctx, "redis.UserSourceCache.User")
+	defer span.End()
+
 	// Check for cached user value.
-	cached := &ghsearch.User{}
-	hit, err := c.cache.Get(ctx, username, cached)
+	cached := cachedUser{}
+	hit, err := c.cache.Get(ctx, userKey(username), &cached)
 	if err != nil {
 		return nil, err
 	}
-	if hit && cached != nil {
-		return cached, nil
+	if r, ok := c.cachedResult(ctx, username, cached, hit); ok {
+		return r.User, r.Err
 	}
 
 	// Get a new user user value.
-	user, err := c.userSrc.User(ctx, username)
+	user, err := c.fetch(ctx, username)
+	if err != nil {
+		return c.staleResult(ctx, username, cached, hit, err)
+	}
+	return user, nil
+}
+
+// Users returns users details resolving all cached users in a single round
+// trip, only the misses are fetched from the source and written back in a
+// single pipeline.
+func (c *UserSourceCache) Users(ctx context.Context, usernames []string) ([]ghsearch.UserResult, error) {
+	ctx, span := tracer().Start(ctx, "redis.UserSourceCache.Users", trace.WithAttributes(
+		attribute.Int("cache.usernames.count", len(usernames)),
+	))
+	defer span.End()
+
+	keys := make([]string, len(usernames))
+	for i, uname := range usernames {
+		keys[i] = userKey(uname)
+	}
+	cached := make([]cachedUser, len(usernames))
+	hits, err := c.cache.GetMulti(ctx, keys, func(i int) interface{} {
+		return &cached[i]
+	})
 	if err != nil {
 		return nil, err
 	}
-	if err = c.cache.Set(ctx, username, user, userCacheExpr); err != nil {
+
+	results := make([]ghsearch.UserResult, len(usernames))
+	var misses []int
+	var missUsernames []string
+	for i, uname := range usernames {
+		if r, ok := c.cachedResult(ctx, uname, cached[i], hits[i]); ok {
+			results[i] = r
+			continue
+		}
+		misses = append(misses, i)
+		missUsernames = append(missUsernames, uname)
+	}
+	if len(misses) == 0 {
+		return results, nil
+	}
+
+	// Get new user values of the misses.
+	found := ghsearch.SourceUsers(ctx, c.userSrc, missUsernames)
+	var items []Item
+	for j, i := range misses {
+		results[i] = found[j]
+		if it, ok := c.cacheItem(found[j].Username, found[j].User, found[j].Err); ok {
+			items = append(items, it)
+		}
+	}
+	if err = c.cache.SetMulti(ctx, items); err != nil {
+		// Mirrors single lookup that fails when caching fails.
+		for _, i := range misses {
+			if results[i].Err == nil {
+				results[i] = ghsearch.UserResult{Username: usernames[i], Err: err}
+			}
+		}
+	}
+	for _, i := range misses {
+		if results[i].Err != nil {
+			results[i].User, results[i].Err = c.staleResult(ctx, usernames[i], cached[i], hits[i], results[i].Err)
+		}
+	}
+	return results, nil
+}
+
+// cachedResult resolves a lookup result from cached value, ok is false
+// when a new user value is required from the source. Caller cache policy
+// can skip the cache or change how old cached user is served.
+func (c *UserSourceCache) cachedResult(ctx context.Context, username string, cached cachedUser, hit bool) (r ghsearch.UserResult, ok bool) {
+	r.Username = username
+	policy := ghsearch.CachePolicyFrom(ctx)
+	if policy.NoCache {
+		c.observe(ctx, username, cacheResultBypass)
+		return r, false
+	}
+	if hit && cached.NotFound {
+		c.observe(ctx, username, cacheResultHit)
+		r.Err = ghsearch.ErrUserNotFound
+		return r, true
+	}
+	if !hit || cached.User == nil {
+		c.observe(ctx, username, cacheResultMiss)
+		return r, false
+	}
+
+	maxAge := userCacheSoftExpr
+	if policy.MaxAge > 0 {
+		maxAge = policy.MaxAge
+	}
+	age := time.Since(cached.CachedAt)
+	if age >= maxAge {
+		c.observe(ctx, username, cacheResultMiss)
+		return r, false
+	}
+	if age >= userCacheExpr {
+		c.observe(ctx, username, cacheResultStale)
+		c.refresh(ctx, username)
+		ghsearch.MarkStale(ctx, username)
+	} else {
+		c.observe(ctx, username, cacheResultHit)
+	}
+	r.User = cached.User
+	return r, true
+}
+
+// observe reports cache lookup result to observer and current span.
+func (c *UserSourceCache) observe(ctx context.Context, username, result string) {
+	trace.SpanFromContext(ctx).AddEvent("cache lookup", trace.WithAttributes(
+		attribute.String("cache.username", username),
+		attribute.String("cache.result", result),
+	))
+	if c.observer != nil {
+		c.observer.CacheResult("redis", result)
+	}
+}
+
+// staleResult serves cached user when the source fails, not found
+// is returned as-is since the user no longer exists.
+func (c *UserSourceCache) staleResult(ctx context.Context, username string, cached cachedUser, hit bool, err error) (*ghsearch.User, error) {
+	if hit && cached.User != nil && !errors.Is(err, ghsearch.ErrUserNotFound) {
+		ghsearch.MarkStale(ctx, username)
+		return cached.User, nil
+	}
+	return nil, err
+}
+
+// fetch gets a new user value from the source and caches it.
+func (c *UserSourceCache) fetch(ctx context.Context, username string) (*ghsearch.User, error) {
+	user, err := c.userSrc.User(ctx, username)
+	if it, ok := c.cacheItem(username, user, err); ok {
+		if err := c.cache.Set(ctx, it.Key, it.Val, it.Expr); err != nil {
+			return nil, err
+		}
+	}
+	return user, err
+}
+
+// cacheItem returns cache item of a source lookup result, ok is false
+// when the result should not be cached.
+func (c *UserSourceCache) cacheItem(username string, user *ghsearch.User, err error) (it Item, ok bool) {
+	if errors.Is(err, ghsearch.ErrUserNotFound) {
+		if c.notFoundExpr <= 0 {
+			return it, false
+		}
+		tombstone := cachedUser{CachedAt: time.Now(), NotFound: true}
+		return Item{Key: userKey(username), Val: tombstone, Expr: c.notFoundExpr}, true
+	}
+	if err != nil || user == nil {
+		return it, false
+	}
+	return Item{Key: userKey(username), Val: cachedUser{User: user, CachedAt: time.Now()}, Expr: userCacheHardExpr}, true
+}
+
+// refresh fetches a new user value in the background, failures are
+// ignored since the cached user stays until hard expiry.
+func (c *UserSourceCache) refresh(ctx context.Context, username string) {
+	// Detached from the request but keeps its id and a link to its span
+	// so the refresh can be traced back to the request that triggered it.
+	reqID := ghsearch.RequestIDFrom(ctx)
+	link := trace.LinkFromContext(ctx)
+	go func() {
+		var leader bool
+		c.refreshGroup.Do(username, func() (interface{}, error) {
+			leader = true
+			ctx := ghsearch.WithRequestID(context.Background(), reqID)
+			ctx, span := tracer().Start(ctx, "redis.UserSourceCache.refresh", trace.WithLinks(link), trace.WithAttributes(
+				attribute.String("cache.username", username),
+			))
+			defer span.End()
+			ctx, cancel := context.WithTimeout(ctx, refreshTimeout)
+			defer cancel()
+			return c.fetch(ctx, username)
+		})
+		if !leader && c.observer != nil {
+			c.observer.RequestDeduped("cache_refresh")
+		}
+	}()
+}
+
+// CachedUser returns cached user entry details, returns ErrCacheMiss
+// when user is not cached.
+func (c *UserSourceCache) CachedUser(ctx context.Context, username string) (*ghsearch.CachedUser, error) {
+	cached := cachedUser{}
+	hit, ttl, err := c.cache.GetTTL(ctx, userKey(username), &cached)
+	if err != nil {
 		return nil, err
 	}
-	return user, nil
+	if !hit {
+		return nil, ghsearch.ErrCacheMiss
+	}
+
+	return &ghsearch.CachedUser{
+		Username: username,
+		User:     cached.User,
+		NotFound: cached.NotFound,
+		CachedAt: cached.CachedAt,
+		TTL:      ttl,
+	}, nil
+}
+
+// DeleteUser removes cached user, returns ErrCacheMiss when user is not cached.
+func (c *UserSourceCache) DeleteUser(ctx context.Context, username string) error {
+	n, err := c.cache.Delete(ctx, userKey(username))
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ghsearch.ErrCacheMiss
+	}
+	return nil
+}
+
+// DeleteKeys removes cached users with username matching glob-style pattern,
+// other keys on the same redis are never matched.
+func (c *UserSourceCache) DeleteKeys(ctx context.Context, pattern string) (int, error) {
+	return c.cache.DeleteMatch(ctx, userKey(pattern))
+}
+
+// userKey returns cache key of username.
+func userKey(username string) string {
+	return userKeyPrefix + username
+}
+
+// RefreshUser fetches a new user value from the source regardless of
+// cached user freshness.
+func (c *UserSourceCache) RefreshUser(ctx context.Context, username string) (*ghsearch.User, error) {
+	return c.fetch(ctx, username)
+}
+
+// SetObserver enables cache instrumentation.
+func (c *UserSourceCache) SetObserver(o Observer) {
+	c.observer = o
+}
+
+// SetNotFoundExpr sets how long not found users are cached, zero disables it.
+func (c *UserSourceCache) SetNotFoundExpr(d time.Duration) {
+	c.notFoundExpr = d
 }
 
 // NewUserSource creates new instance of user source with cache.
-func NewUserSource(c *Client, us ghsearch.UserSource) *UserSourceCache {
-	return &UserSourceCache{c, us}
+func NewUserSource(c Cache, us ghsearch.UserSource) *UserSourceCache {
+	return &UserSourceCache{cache: c, userSrc: us, notFoundExpr: DefaultNotFoundCacheExpr}
 }
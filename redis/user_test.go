@@ -0,0 +1,540 @@
+package redis_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"path"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kudarap/ghsearch"
+	"github.com/kudarap/ghsearch/redis"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestUserSourceCache_User_Expiry(t *testing.T) {
+	cached := &ghsearch.User{Login: "kudarap", Name: "cached"}
+	fetched := &ghsearch.User{Login: "kudarap", Name: "fetched"}
+	testcases := []struct {
+		name string
+		// deps
+		age       time.Duration
+		sourceErr error
+		// returns
+		want       *ghsearch.User
+		wantErr    error
+		wantCalls  int
+		wantCached *ghsearch.User
+	}{
+		{
+			"fresh served without source call",
+			time.Minute,
+			nil,
+			cached,
+			nil,
+			0,
+			cached,
+		},
+		{
+			"soft expired served and refreshed in background",
+			5 * time.Minute,
+			nil,
+			cached,
+			nil,
+			1,
+			fetched,
+		},
+		{
+			"soft expired served when background refresh fails",
+			5 * time.Minute,
+			ghsearch.ErrUserSourceTimeout,
+			cached,
+			nil,
+			1,
+			cached,
+		},
+		{
+			"hard expired fetched from source",
+			30 * time.Minute,
+			nil,
+			fetched,
+			nil,
+			1,
+			fetched,
+		},
+		{
+			"hard expired served when source fails",
+			30 * time.Minute,
+			ghsearch.ErrUserSourceTimeout,
+			cached,
+			nil,
+			1,
+			cached,
+		},
+		{
+			"hard expired not served when user no longer exists",
+			30 * time.Minute,
+			ghsearch.ErrUserNotFound,
+			nil,
+			ghsearch.ErrUserNotFound,
+			1,
+			nil,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			store := newMockedCache()
+			store.put(t, "user:kudarap", cachedEntry{User: cached, CachedAt: time.Now().Add(-tc.age)})
+			source := &mockedUserSource{user: fetched, err: tc.sourceErr}
+			uc := redis.NewUserSource(store, source)
+
+			got, gotErr := uc.User(ctx, "kudarap")
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("\ngot: \n\t%#v \nwant: \n\t%#v", got, tc.want)
+			}
+			if !errors.Is(gotErr, tc.wantErr) {
+				t.Errorf("err: %#v, want: %#v", gotErr, tc.wantErr)
+			}
+
+			// Background refresh settles after the lookup returns.
+			var gotCached *ghsearch.User
+			for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+				gotCached = store.get(t, "user:kudarap").User
+				if source.callCount() == tc.wantCalls && reflect.DeepEqual(gotCached, tc.wantCached) {
+					break
+				}
+			}
+			if got := source.callCount(); got != tc.wantCalls {
+				t.Errorf("calls: %d, want: %d", got, tc.wantCalls)
+			}
+			if !reflect.DeepEqual(gotCached, tc.wantCached) {
+				t.Errorf("\ncached: \n\t%#v \nwant: \n\t%#v", gotCached, tc.wantCached)
+			}
+		})
+	}
+}
+
+func TestUserSourceCache_User_RefreshTrace(t *testing.T) {
+	exp := tracetest.NewInMemoryExporter()
+	prev := otel.GetTracerProvider()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exp))
+	otel.SetTracerProvider(tp)
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+
+	store := newMockedCache()
+	store.put(t, "user:kudarap", cachedEntry{User: &ghsearch.User{Login: "kudarap"}, CachedAt: time.Now().Add(-5 * time.Minute)})
+	source := &mockedUserSource{user: &ghsearch.User{Login: "kudarap"}}
+	uc := redis.NewUserSource(store, source)
+
+	ctx, span := tp.Tracer("test").Start(context.Background(), "request")
+	if _, err := uc.User(ctx, "kudarap"); err != nil {
+		t.Fatalf("err: %#v, want: nil", err)
+	}
+	span.End()
+
+	// Refresh span is a root of its own linked to the request span.
+	var refresh *tracetest.SpanStub
+	for deadline := time.Now().Add(time.Second); refresh == nil && time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		for _, s := range exp.GetSpans() {
+			if s.Name == "redis.UserSourceCache.refresh" {
+				s := s
+				refresh = &s
+			}
+		}
+	}
+	if refresh == nil {
+		t.Fatal("refresh span should be recorded")
+	}
+	if refresh.Parent.IsValid() {
+		t.Errorf("refresh parent: %s, want: none", refresh.Parent.SpanID())
+	}
+	if len(refresh.Links) != 1 || refresh.Links[0].SpanContext.TraceID() != span.SpanContext().TraceID() {
+		t.Errorf("refresh links: %#v, want link to trace %s", refresh.Links, span.SpanContext().TraceID())
+	}
+}
+
+func TestUserSourceCache_User_NotFound(t *testing.T) {
+	user := &ghsearch.User{Login: "kudarap"}
+	tombstone := &cachedEntry{CachedAt: time.Now(), NotFound: true}
+	testcases := []struct {
+		name string
+		// deps
+		cached       *cachedEntry
+		notFoundExpr time.Duration
+		policy       ghsearch.CachePolicy
+		source       *mockedUserSource
+		// returns
+		want      *ghsearch.User
+		wantErr   error
+		wantCalls int
+		wantExpr  time.Duration
+	}{
+		{
+			"not found cached with short expiry",
+			nil,
+			redis.DefaultNotFoundCacheExpr,
+			ghsearch.CachePolicy{},
+			&mockedUserSource{err: ghsearch.ErrUserNotFound},
+			nil,
+			ghsearch.ErrUserNotFound,
+			1,
+			redis.DefaultNotFoundCacheExpr,
+		},
+		{
+			"not found not cached when disabled",
+			nil,
+			0,
+			ghsearch.CachePolicy{},
+			&mockedUserSource{err: ghsearch.ErrUserNotFound},
+			nil,
+			ghsearch.ErrUserNotFound,
+			1,
+			-1,
+		},
+		{
+			"tombstone served without source call",
+			tombstone,
+			redis.DefaultNotFoundCacheExpr,
+			ghsearch.CachePolicy{},
+			&mockedUserSource{user: user},
+			nil,
+			ghsearch.ErrUserNotFound,
+			0,
+			0,
+		},
+		{
+			"tombstone bypassed on no cache",
+			tombstone,
+			redis.DefaultNotFoundCacheExpr,
+			ghsearch.CachePolicy{NoCache: true},
+			&mockedUserSource{user: user},
+			user,
+			nil,
+			1,
+			time.Hour,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := ghsearch.WithCachePolicy(context.Background(), tc.policy)
+			store := newMockedCache()
+			if tc.cached != nil {
+				store.put(t, "user:kudarap", *tc.cached)
+			}
+			uc := redis.NewUserSource(store, tc.source)
+			uc.SetNotFoundExpr(tc.notFoundExpr)
+
+			got, gotErr := uc.User(ctx, "kudarap")
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("\ngot: \n\t%#v \nwant: \n\t%#v", got, tc.want)
+			}
+			if !errors.Is(gotErr, tc.wantErr) {
+				t.Errorf("err: %#v, want: %#v", gotErr, tc.wantErr)
+			}
+			if got := tc.source.callCount(); got != tc.wantCalls {
+				t.Errorf("calls: %d, want: %d", got, tc.wantCalls)
+			}
+			if got := store.expr("user:kudarap"); got != tc.wantExpr {
+				t.Errorf("expr: %s, want: %s", got, tc.wantExpr)
+			}
+		})
+	}
+}
+
+func TestUserSourceCache_Users(t *testing.T) {
+	now := time.Now()
+	fresh := &ghsearch.User{Login: "fresh"}
+	stale := &ghsearch.User{Login: "stale", Name: "cached"}
+	failing := &ghsearch.User{Login: "failing", Name: "cached"}
+	miss := &ghsearch.User{Login: "miss"}
+	refetched := &ghsearch.User{Login: "stale", Name: "fetched"}
+	testcases := []struct {
+		name string
+		// deps
+		cached map[string]cachedEntry
+		source *mockedBatchUserSource
+		// args
+		usernames []string
+		// returns
+		want        []ghsearch.UserResult
+		wantBatches [][]string
+		wantWritten []string
+	}{
+		{
+			"mixed hits misses tombstones and stale",
+			map[string]cachedEntry{
+				"fresh":     {User: fresh, CachedAt: now.Add(-time.Minute)},
+				"stale":     {User: stale, CachedAt: now.Add(-30 * time.Minute)},
+				"failing":   {User: failing, CachedAt: now.Add(-30 * time.Minute)},
+				"tombstone": {CachedAt: now, NotFound: true},
+			},
+			&mockedBatchUserSource{results: map[string]ghsearch.UserResult{
+				"miss":    {Username: "miss", User: miss},
+				"stale":   {Username: "stale", User: refetched},
+				"failing": {Username: "failing", Err: ghsearch.ErrUserSourceTimeout},
+			}},
+			[]string{"miss", "fresh", "stale", "tombstone", "missing", "failing"},
+			[]ghsearch.UserResult{
+				{Username: "miss", User: miss},
+				{Username: "fresh", User: fresh},
+				{Username: "stale", User: refetched},
+				{Username: "tombstone", Err: ghsearch.ErrUserNotFound},
+				{Username: "missing", Err: ghsearch.ErrUserNotFound},
+				{Username: "failing", User: failing},
+			},
+			[][]string{{"miss", "stale", "missing", "failing"}},
+			[]string{"user:miss", "user:stale", "user:missing"},
+		},
+		{
+			"all cached",
+			map[string]cachedEntry{
+				"fresh":     {User: fresh, CachedAt: now.Add(-time.Minute)},
+				"tombstone": {CachedAt: now, NotFound: true},
+			},
+			&mockedBatchUserSource{},
+			[]string{"tombstone", "fresh"},
+			[]ghsearch.UserResult{
+				{Username: "tombstone", Err: ghsearch.ErrUserNotFound},
+				{Username: "fresh", User: fresh},
+			},
+			nil,
+			nil,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			store := newMockedCache()
+			for k, e := range tc.cached {
+				store.put(t, "user:"+k, e)
+			}
+			uc := redis.NewUserSource(store, tc.source)
+
+			got, err := uc.Users(context.Background(), tc.usernames)
+			if err != nil {
+				t.Fatalf("err: %#v, want: nil", err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("\ngot: \n\t%#v \nwant: \n\t%#v", got, tc.want)
+			}
+			if !reflect.DeepEqual(tc.source.batches, tc.wantBatches) {
+				t.Errorf("\nbatches: \n\t%#v \nwant: \n\t%#v", tc.source.batches, tc.wantBatches)
+			}
+			if !reflect.DeepEqual(store.written, tc.wantWritten) {
+				t.Errorf("\nwritten: \n\t%#v \nwant: \n\t%#v", store.written, tc.wantWritten)
+			}
+		})
+	}
+}
+
+func TestUserSourceCache_DeleteKeys(t *testing.T) {
+	c, mr := newTestClient(t)
+	uc := redis.NewUserSource(c, &mockedUserSource{user: &ghsearch.User{Login: "kudarap"}})
+	ctx := context.Background()
+	for _, uname := range []string{"kudarap", "dazz"} {
+		if _, err := uc.RefreshUser(ctx, uname); err != nil {
+			t.Fatalf("err: %#v, want: nil", err)
+		}
+	}
+	mr.Set("gh-search-apikey:digest", "{}")
+	mr.Set("gh-search-ratelimit:core:token", "{}")
+
+	// Wildcard only matches cached users.
+	n, err := uc.DeleteKeys(ctx, "*")
+	if err != nil {
+		t.Fatalf("err: %#v, want: nil", err)
+	}
+	if n != 2 {
+		t.Errorf("deleted: %d, want: %d", n, 2)
+	}
+	want := []string{"gh-search-apikey:digest", "gh-search-ratelimit:core:token"}
+	if got := mr.Keys(); !reflect.DeepEqual(got, want) {
+		t.Errorf("\ngot: \n\t%#v \nwant: \n\t%#v", got, want)
+	}
+}
+
+// cachedEntry mirrors the stored format of cached users.
+type cachedEntry struct {
+	User     *ghsearch.User `json:"user"`
+	CachedAt time.Time      `json:"cached_at"`
+	NotFound bool           `json:"not_found,omitempty"`
+}
+
+// mockedCache stores encoded values by key and records their expiry
+// without expiring them.
+type mockedCache struct {
+	mu    sync.Mutex
+	vals  map[string][]byte
+	exprs map[string]time.Duration
+
+	// written keeps keys set in batches.
+	written []string
+}
+
+func newMockedCache() *mockedCache {
+	return &mockedCache{vals: map[string][]byte{}, exprs: map[string]time.Duration{}}
+}
+
+func (c *mockedCache) put(t *testing.T, key string, e cachedEntry) {
+	t.Helper()
+	if err := c.Set(context.Background(), key, e, 0); err != nil {
+		t.Fatalf("err: %#v, want: nil", err)
+	}
+}
+
+func (c *mockedCache) get(t *testing.T, key string) cachedEntry {
+	t.Helper()
+	var e cachedEntry
+	if _, err := c.Get(context.Background(), key, &e); err != nil {
+		t.Fatalf("err: %#v, want: nil", err)
+	}
+	return e
+}
+
+// expr returns expiry the key was set with, -1 when not set.
+func (c *mockedCache) expr(key string) time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	d, ok := c.exprs[key]
+	if !ok {
+		return -1
+	}
+	return d
+}
+
+func (c *mockedCache) Get(ctx context.Context, key string, out interface{}) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	b, ok := c.vals[key]
+	if !ok {
+		return false, nil
+	}
+	return true, json.Unmarshal(b, out)
+}
+
+func (c *mockedCache) GetMulti(ctx context.Context, keys []string, newOut func(i int) interface{}) ([]bool, error) {
+	hits := make([]bool, len(keys))
+	for i, k := range keys {
+		ok, err := c.Get(ctx, k, newOut(i))
+		if err != nil {
+			return nil, err
+		}
+		hits[i] = ok
+	}
+	return hits, nil
+}
+
+func (c *mockedCache) GetTTL(ctx context.Context, key string, out interface{}) (bool, time.Duration, error) {
+	ok, err := c.Get(ctx, key, out)
+	return ok, 0, err
+}
+
+func (c *mockedCache) Set(ctx context.Context, key string, val interface{}, expr time.Duration) error {
+	b, err := json.Marshal(val)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.vals[key] = b
+	c.exprs[key] = expr
+	return nil
+}
+
+func (c *mockedCache) SetMulti(ctx context.Context, items []redis.Item) error {
+	for _, it := range items {
+		c.mu.Lock()
+		c.written = append(c.written, it.Key)
+		c.mu.Unlock()
+		if err := c.Set(ctx, it.Key, it.Val, it.Expr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *mockedCache) Delete(ctx context.Context, keys ...string) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var n int
+	for _, k := range keys {
+		if _, ok := c.vals[k]; ok {
+			delete(c.vals, k)
+			delete(c.exprs, k)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (c *mockedCache) DeleteMatch(ctx context.Context, pattern string) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var n int
+	for k := range c.vals {
+		if ok, _ := path.Match(pattern, k); ok {
+			delete(c.vals, k)
+			delete(c.exprs, k)
+			n++
+		}
+	}
+	return n, nil
+}
+
+// mockedUserSource returns the same result for every username.
+type mockedUserSource struct {
+	user *ghsearch.User
+	err  error
+
+	mu    sync.Mutex
+	calls []string
+}
+
+func (s *mockedUserSource) User(ctx context.Context, username string) (*ghsearch.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls = append(s.calls, username)
+	if s.err != nil {
+		return nil, s.err
+	}
+	return s.user, nil
+}
+
+func (s *mockedUserSource) callCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.calls)
+}
+
+// mockedBatchUserSource resolves usernames from results,
+// usernames without result are not found.
+type mockedBatchUserSource struct {
+	results map[string]ghsearch.UserResult
+	batches [][]string
+}
+
+func (s *mockedBatchUserSource) User(ctx context.Context, username string) (*ghsearch.User, error) {
+	rr, err := s.Users(ctx, []string{username})
+	if err != nil {
+		return nil, err
+	}
+	return rr[0].User, rr[0].Err
+}
+
+func (s *mockedBatchUserSource) Users(ctx context.Context, usernames []string) ([]ghsearch.UserResult, error) {
+	s.batches = append(s.batches, usernames)
+	results := make([]ghsearch.UserResult, len(usernames))
+	for i, uname := range usernames {
+		r, ok := s.results[uname]
+		if !ok {
+			r = ghsearch.UserResult{Username: uname, Err: ghsearch.ErrUserNotFound}
+		}
+		results[i] = r
+	}
+	return results, nil
+}
@@ -0,0 +1,31 @@
+package ghsearch
+
+import "context"
+
+type requestIDKey struct{}
+
+// WithRequestID returns a copy of ctx that carries request id
+// to trace a request end to end.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFrom returns request id carried by ctx, empty when none.
+func RequestIDFrom(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+type sourceTokenKey struct{}
+
+// WithSourceToken returns a copy of ctx that carries caller own access token
+// for the user source to use instead of the service tokens.
+func WithSourceToken(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, sourceTokenKey{}, token)
+}
+
+// SourceTokenFrom returns caller access token carried by ctx, empty when none.
+func SourceTokenFrom(ctx context.Context) string {
+	token, _ := ctx.Value(sourceTokenKey{}).(string)
+	return token
+}
@@ -0,0 +1,19 @@
+package ghsearch
+
+import (
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer returns tracer of the global tracer provider at the time of use
+// so a replaced provider takes effect, spans are not recorded until its set.
+func tracer() trace.Tracer {
+	return otel.Tracer("github.com/kudarap/ghsearch")
+}
+
+// recordError marks span as failed with err.
+func recordError(span trace.Span, err error) {
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
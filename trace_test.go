@@ -0,0 +1,43 @@
+package ghsearch_test
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/kudarap/ghsearch"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestUserService_Users_Trace(t *testing.T) {
+	exp := tracetest.NewInMemoryExporter()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exp)))
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+
+	source := &mockedUserSource{users: map[string]*ghsearch.User{"kudarap": {Name: "james"}}}
+	svc := ghsearch.NewUserService(source)
+	if _, err := svc.Users(context.Background(), []string{"kudarap", "dazz"}); err != nil {
+		t.Fatalf("err: %#v, want: nil", err)
+	}
+
+	// One child span for each username lookup.
+	var got []string
+	for _, s := range exp.GetSpans() {
+		name := s.Name
+		for _, attr := range s.Attributes {
+			if attr.Key == "username" {
+				name += " " + attr.Value.AsString()
+			}
+		}
+		got = append(got, name)
+	}
+	sort.Strings(got)
+	want := []string{"UserService.Users", "UserSource.User dazz", "UserSource.User kudarap"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("\ngot: \n\t%#v \nwant: \n\t%#v", got, want)
+	}
+}
@@ -0,0 +1,41 @@
+package tracing_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kudarap/ghsearch/tracing"
+	"go.opentelemetry.io/otel"
+)
+
+func TestSetup(t *testing.T) {
+	testcases := []struct {
+		name string
+		// args
+		exporter string
+		// returns
+		wantErr bool
+	}{
+		{"disabled", tracing.ExporterNone, false},
+		{"stdout", tracing.ExporterStdout, false},
+		{"unknown", "jaeger", true},
+	}
+	// Setup replaces global tracer provider and propagator.
+	prevTP, prevProp := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	t.Cleanup(func() {
+		otel.SetTracerProvider(prevTP)
+		otel.SetTextMapPropagator(prevProp)
+	})
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			shutdown, err := tracing.Setup(ctx, tracing.Config{ServiceName: "test", Exporter: tc.exporter})
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("err: %#v, want err: %v", err, tc.wantErr)
+			}
+			if err == nil {
+				shutdown(ctx)
+			}
+		})
+	}
+}
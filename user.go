@@ -3,9 +3,12 @@ package ghsearch
 import (
 	"context"
 	"errors"
+	"fmt"
 	"strings"
+	"sync"
 
-	"golang.org/x/sync/errgroup"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 var (
@@ -14,12 +17,44 @@ var (
 
 	// ErrUserNotFound indicates user details can't be found from the source.
 	ErrUserNotFound = errors.New("user not found")
+
+	// ErrInvalidUsername indicates username does not follow GitHub login rules.
+	ErrInvalidUsername = errors.New("invalid username")
 )
 
 // MaxUsersInputLength represents allowed maximum number of username input.
 const MaxUsersInputLength = 10
 
-// User represents a user details.
+// MaxUsernameLength represents maximum length of GitHub login.
+const MaxUsernameLength = 39
+
+// ValidateUsername checks username against GitHub login rules, it may only
+// contain alphanumeric characters or single hyphens, cannot begin or end with
+// a hyphen and is between 1 and MaxUsernameLength characters.
+func ValidateUsername(username string) error {
+	if username == "" {
+		return fmt.Errorf("%w: empty", ErrInvalidUsername)
+	}
+	if len(username) > MaxUsernameLength {
+		return fmt.Errorf("%w: longer than %d characters", ErrInvalidUsername, MaxUsernameLength)
+	}
+	for i := 0; i < len(username); i++ {
+		c := username[i]
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		case c != '-':
+			return fmt.Errorf("%w: only alphanumeric characters or hyphens allowed", ErrInvalidUsername)
+		case i == 0 || i == len(username)-1:
+			return fmt.Errorf("%w: cannot begin or end with a hyphen", ErrInvalidUsername)
+		case username[i-1] == '-':
+			return fmt.Errorf("%w: consecutive hyphens not allowed", ErrInvalidUsername)
+		}
+	}
+	return nil
+}
+
+// User represents a user details. It only holds public profile fields so
+// users fetched with caller own access token are safe to cache and share.
 type User struct {
 	Name        string `json:"name"`
 	Login       string `json:"login"`
@@ -28,10 +63,23 @@ type User struct {
 	PublicRepos int    `json:"public_repos"`
 }
 
+// UserResult represents a lookup result of a single requested username,
+// it either contains the User or an error why it cant be retrieved.
+type UserResult struct {
+	Username string
+	User     *User
+	Err      error
+
+	// Stale indicates User was served from an outdated copy.
+	Stale bool
+}
+
 // UserService provides access to user service.
 type UserService interface {
-	// Users returns a list of user base on usernames.
-	Users(ctx context.Context, usernames []string) ([]*User, error)
+	// Users returns a lookup result for each username in the same order
+	// as the input. Failure of a single lookup will not fail the others
+	// and only returns an error when the whole input is invalid.
+	Users(ctx context.Context, usernames []string) ([]UserResult, error)
 }
 
 // UserSource provides operation for retrieving user.
@@ -42,12 +90,21 @@ type UserSource interface {
 	User(ctx context.Context, username string) (*User, error)
 }
 
+// BatchUserSource provides operation for retrieving multiple users at once.
+type BatchUserSource interface {
+	UserSource
+
+	// Users returns a lookup result for each username in the same order
+	// as the input. Error only returned when the whole batch failed.
+	Users(ctx context.Context, usernames []string) ([]UserResult, error)
+}
+
 // DefaultUserService represents a default implementation of user service.
 type DefaultUserService struct {
 	source UserSource
 }
 
-func (us *DefaultUserService) Users(ctx context.Context, usernames []string) ([]*User, error) {
+func (us *DefaultUserService) Users(ctx context.Context, usernames []string) ([]UserResult, error) {
 	usernames = cleanUsernames(usernames)
 	length := len(usernames)
 	if length == 0 {
@@ -57,28 +114,113 @@ func (us *DefaultUserService) Users(ctx context.Context, usernames []string) ([]
 		return nil, ErrTooManyInput
 	}
 
-	users := make([]*User, length)
+	ctx, span := tracer().Start(ctx, "UserService.Users", trace.WithAttributes(
+		attribute.Int("usernames.count", length),
+	))
+	defer span.End()
+
+	// Invalid usernames never reach the source since they could
+	// be read as other endpoints or waste its rate limit.
+	results := make([]UserResult, len(usernames))
+	var lookups []string
+	var lookupIdx []int
+	for i, uname := range usernames {
+		results[i].Username = uname
+		if uname == "" {
+			results[i].Err = ErrUserNotFound
+			continue
+		}
+		if err := ValidateUsername(uname); err != nil {
+			results[i].Err = err
+			continue
+		}
+		lookups = append(lookups, uname)
+		lookupIdx = append(lookupIdx, i)
+	}
+	if len(lookups) == 0 {
+		return results, nil
+	}
+
+	ctx, stale := withStaleRecorder(ctx)
+	found := SourceUsers(ctx, us.source, lookups)
+	for j, i := range lookupIdx {
+		if found[j].Err != nil {
+			results[i].Err = userResultError(found[j].Err)
+		} else {
+			results[i].User = found[j].User
+		}
+		results[i].Stale = results[i].User != nil && stale.has(results[i].Username)
+	}
+	return results, nil
+}
+
+// SourceUsers returns a lookup result for each username in the same order as
+// the input. It uses a single call when the source supports batch lookups,
+// otherwise it gets user details from the source concurrently.
+func SourceUsers(ctx context.Context, source UserSource, usernames []string) []UserResult {
+	if bs, ok := source.(BatchUserSource); ok {
+		return batchUsers(ctx, bs, usernames)
+	}
+
+	results := make([]UserResult, len(usernames))
 
 	// Getting user details concurrently, since we already know the length of the input
 	// its safe to process this way. when we have unknown number of input it might be
 	// better to use a worker or semaphore.
-	errG, ctx := errgroup.WithContext(ctx)
+	var wg sync.WaitGroup
 	for i, uname := range usernames {
 		i, uname := i, uname // https://golang.org/doc/faq#closures_and_goroutines
-		errG.Go(func() error {
-			user, err := us.source.User(ctx, uname)
-			if err != nil && !errors.Is(err, ErrUserNotFound) {
-				return NewSourceError(err)
+		results[i].Username = uname
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, span := tracer().Start(ctx, "UserSource.User", trace.WithAttributes(
+				attribute.String("username", uname),
+			))
+			defer span.End()
+
+			results[i].User, results[i].Err = source.User(ctx, uname)
+			if err := results[i].Err; err != nil && !errors.Is(err, ErrUserNotFound) {
+				recordError(span, err)
 			}
-			users[i] = user
-			return nil
-		})
+		}()
 	}
-	if err := errG.Wait(); err != nil {
-		return nil, err
+	wg.Wait()
+
+	return results
+}
+
+// batchUsers gets user details in a single source call, whole batch failure
+// is set on each result.
+func batchUsers(ctx context.Context, source BatchUserSource, usernames []string) []UserResult {
+	ctx, span := tracer().Start(ctx, "UserSource.Users", trace.WithAttributes(
+		attribute.Int("usernames.count", len(usernames)),
+	))
+	defer span.End()
+
+	batch, err := source.Users(ctx, usernames)
+	if err == nil && len(batch) != len(usernames) {
+		err = errors.New("batch result length mismatch")
 	}
+	if err == nil {
+		return batch
+	}
+	recordError(span, err)
 
-	return users, nil
+	results := make([]UserResult, len(usernames))
+	for i, uname := range usernames {
+		results[i] = UserResult{Username: uname, Err: err}
+	}
+	return results
+}
+
+// UserSourceFunc is an adapter to use ordinary function as a user source.
+type UserSourceFunc func(ctx context.Context, username string) (*User, error)
+
+// User calls f(ctx, username).
+func (f UserSourceFunc) User(ctx context.Context, username string) (*User, error) {
+	return f(ctx, username)
 }
 
 // NewUserService return default user service.
@@ -93,3 +235,19 @@ func cleanUsernames(usernames []string) []string {
 	}
 	return uu
 }
+
+// userResultError keeps known source errors as-is and wraps
+// everything else as a SourceError.
+func userResultError(err error) error {
+	switch {
+	case errors.Is(err, ErrUserNotFound),
+		errors.Is(err, ErrUserSourceTimeout),
+		errors.Is(err, ErrUserSourceRateLimited),
+		errors.Is(err, ErrUserSourceUnavailable),
+		errors.Is(err, ErrUserSourceUnauthorized):
+		return err
+	case errors.Is(err, context.DeadlineExceeded):
+		return ErrUserSourceTimeout
+	}
+	return NewSourceError(err)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -20,7 +21,7 @@ func TestUserService_Users(t *testing.T) {
 		// args
 		usernames []string
 		// returns
-		want    []*ghsearch.User
+		want    []ghsearch.UserResult
 		wantErr error
 	}{
 		{
@@ -31,8 +32,8 @@ func TestUserService_Users(t *testing.T) {
 				},
 			},
 			[]string{"kudarap"},
-			[]*ghsearch.User{
-				{Name: "james"},
+			[]ghsearch.UserResult{
+				{Username: "kudarap", User: &ghsearch.User{Name: "james"}},
 			},
 			nil,
 		},
@@ -47,10 +48,10 @@ func TestUserService_Users(t *testing.T) {
 				},
 			},
 			[]string{"kudarap", "spec", "dazz"},
-			[]*ghsearch.User{
-				{Name: "james"},
-				{Name: "spectre"},
-				{Name: "dazzle"},
+			[]ghsearch.UserResult{
+				{Username: "kudarap", User: &ghsearch.User{Name: "james"}},
+				{Username: "spec", User: &ghsearch.User{Name: "spectre"}},
+				{Username: "dazz", User: &ghsearch.User{Name: "dazzle"}},
 			},
 			nil,
 		},
@@ -69,10 +70,10 @@ func TestUserService_Users(t *testing.T) {
 				},
 			},
 			[]string{"kudarap", " ", ""},
-			[]*ghsearch.User{
-				{Name: "james"},
-				nil,
-				nil,
+			[]ghsearch.UserResult{
+				{Username: "kudarap", User: &ghsearch.User{Name: "james"}},
+				{Username: "", Err: ghsearch.ErrUserNotFound},
+				{Username: "", Err: ghsearch.ErrUserNotFound},
 			},
 			nil,
 		},
@@ -104,10 +105,24 @@ func TestUserService_Users(t *testing.T) {
 				},
 			},
 			[]string{"jugg", "spec", "dazz"},
-			[]*ghsearch.User{
-				{Name: "juggernaut"},
-				nil,
-				{Name: "dazzle"},
+			[]ghsearch.UserResult{
+				{Username: "jugg", User: &ghsearch.User{Name: "juggernaut"}},
+				{Username: "spec", Err: ghsearch.ErrUserNotFound},
+				{Username: "dazz", User: &ghsearch.User{Name: "dazzle"}},
+			},
+			nil,
+		},
+		{
+			"invalid username",
+			&mockedUserSource{
+				users: map[string]*ghsearch.User{
+					"jugg": {Name: "juggernaut"},
+				},
+			},
+			[]string{"jugg", "../rate_limit"},
+			[]ghsearch.UserResult{
+				{Username: "jugg", User: &ghsearch.User{Name: "juggernaut"}},
+				{Username: "../rate_limit", Err: ghsearch.ValidateUsername("../rate_limit")},
 			},
 			nil,
 		},
@@ -120,8 +135,41 @@ func TestUserService_Users(t *testing.T) {
 				err: errUserSourceCall,
 			},
 			[]string{"jugg", "dazz"},
+			[]ghsearch.UserResult{
+				{Username: "jugg", User: &ghsearch.User{Name: "juggernaut"}},
+				{Username: "dazz", Err: ghsearch.NewSourceError(errUserSourceCall)},
+			},
+			nil,
+		},
+		{
+			"source some timed out",
+			&mockedUserSource{
+				users: map[string]*ghsearch.User{
+					"jugg": {Name: "juggernaut"},
+				},
+				err: context.DeadlineExceeded,
+			},
+			[]string{"jugg", "dazz"},
+			[]ghsearch.UserResult{
+				{Username: "jugg", User: &ghsearch.User{Name: "juggernaut"}},
+				{Username: "dazz", Err: ghsearch.ErrUserSourceTimeout},
+			},
+			nil,
+		},
+		{
+			"source some rate limited",
+			&mockedUserSource{
+				users: map[string]*ghsearch.User{
+					"jugg": {Name: "juggernaut"},
+				},
+				err: ghsearch.ErrUserSourceRateLimited,
+			},
+			[]string{"jugg", "dazz"},
+			[]ghsearch.UserResult{
+				{Username: "jugg", User: &ghsearch.User{Name: "juggernaut"}},
+				{Username: "dazz", Err: ghsearch.ErrUserSourceRateLimited},
+			},
 			nil,
-			ghsearch.NewSourceError(errUserSourceCall),
 		},
 	}
 	for _, tc := range testcases {
@@ -139,6 +187,149 @@ func TestUserService_Users(t *testing.T) {
 	}
 }
 
+func TestValidateUsername(t *testing.T) {
+	testcases := []struct {
+		name string
+		// args
+		username string
+		// returns
+		wantErr bool
+	}{
+		{"empty", "", true},
+		{"alphanumeric", "kudarap", false},
+		{"single hyphens", "ku-da-rap9", false},
+		{"max length", strings.Repeat("a", ghsearch.MaxUsernameLength), false},
+		{"too long", strings.Repeat("a", ghsearch.MaxUsernameLength+1), true},
+		{"leading hyphen", "-kudarap", true},
+		{"trailing hyphen", "kudarap-", true},
+		{"consecutive hyphens", "kuda--rap", true},
+		{"path traversal", "../rate_limit", true},
+		{"query", "a/b?x=1", true},
+		{"underscore", "kuda_rap", true},
+		{"non ascii", "kudaräp", true},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ghsearch.ValidateUsername(tc.username)
+			if (err != nil) != tc.wantErr {
+				t.Errorf("err: %#v, want error: %t", err, tc.wantErr)
+			}
+			if err != nil && !errors.Is(err, ghsearch.ErrInvalidUsername) {
+				t.Errorf("err: %#v, want: %#v", err, ghsearch.ErrInvalidUsername)
+			}
+		})
+	}
+}
+
+func TestUserService_Users_Batch(t *testing.T) {
+	testcases := []struct {
+		name string
+		// deps
+		source *mockedBatchUserSource
+		// args
+		usernames []string
+		// returns
+		want      []ghsearch.UserResult
+		wantCalls int
+	}{
+		{
+			"single call",
+			&mockedBatchUserSource{
+				mockedUserSource: mockedUserSource{
+					users: map[string]*ghsearch.User{
+						"kudarap": {Name: "james"},
+						"dazz":    {Name: "dazzle"},
+					},
+				},
+			},
+			[]string{"kudarap", " ", "spec", "dazz"},
+			[]ghsearch.UserResult{
+				{Username: "kudarap", User: &ghsearch.User{Name: "james"}},
+				{Username: "", Err: ghsearch.ErrUserNotFound},
+				{Username: "spec", Err: ghsearch.ErrUserNotFound},
+				{Username: "dazz", User: &ghsearch.User{Name: "dazzle"}},
+			},
+			1,
+		},
+		{
+			"batch failed",
+			&mockedBatchUserSource{
+				batchErr: errUserSourceCall,
+			},
+			[]string{"kudarap", "dazz"},
+			[]ghsearch.UserResult{
+				{Username: "kudarap", Err: ghsearch.NewSourceError(errUserSourceCall)},
+				{Username: "dazz", Err: ghsearch.NewSourceError(errUserSourceCall)},
+			},
+			1,
+		},
+		{
+			"all empty",
+			&mockedBatchUserSource{},
+			[]string{" ", ""},
+			[]ghsearch.UserResult{
+				{Username: "", Err: ghsearch.ErrUserNotFound},
+				{Username: "", Err: ghsearch.ErrUserNotFound},
+			},
+			0,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			svc := ghsearch.NewUserService(tc.source)
+			got, gotErr := svc.Users(ctx, tc.usernames)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("\ngot: \n\t%#v \nwant: \n\t%#v", got, tc.want)
+			}
+			if gotErr != nil {
+				t.Errorf("err: %#v, want: nil", gotErr)
+			}
+			if tc.source.calls != tc.wantCalls {
+				t.Errorf("calls: %d, want: %d", tc.source.calls, tc.wantCalls)
+			}
+		})
+	}
+}
+
+func TestUserService_Users_Stale(t *testing.T) {
+	source := &staleUserSource{
+		mockedUserSource: mockedUserSource{
+			users: map[string]*ghsearch.User{
+				"kudarap": {Name: "james"},
+				"dazz":    {Name: "dazzle"},
+			},
+		},
+		stale: map[string]bool{"dazz": true, "spec": true},
+	}
+	want := []ghsearch.UserResult{
+		{Username: "kudarap", User: &ghsearch.User{Name: "james"}},
+		{Username: "dazz", User: &ghsearch.User{Name: "dazzle"}, Stale: true},
+		{Username: "spec", Err: ghsearch.ErrUserNotFound},
+	}
+
+	svc := ghsearch.NewUserService(source)
+	got, err := svc.Users(context.Background(), []string{"kudarap", "dazz", "spec"})
+	if err != nil {
+		t.Fatalf("err: %#v, want: nil", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("\ngot: \n\t%#v \nwant: \n\t%#v", got, want)
+	}
+}
+
+type staleUserSource struct {
+	mockedUserSource
+	stale map[string]bool
+}
+
+func (s *staleUserSource) User(ctx context.Context, username string) (*ghsearch.User, error) {
+	if s.stale[username] {
+		ghsearch.MarkStale(ctx, username)
+	}
+	return s.mockedUserSource.User(ctx, username)
+}
+
 type mockedUserSource struct {
 	users           map[string]*ghsearch.User
 	err             error
@@ -158,3 +349,23 @@ func (mus *mockedUserSource) User(ctx context.Context, username string) (*ghsear
 	}
 	return u, nil
 }
+
+type mockedBatchUserSource struct {
+	mockedUserSource
+	batchErr error
+	calls    int
+}
+
+func (mbs *mockedBatchUserSource) Users(ctx context.Context, usernames []string) ([]ghsearch.UserResult, error) {
+	mbs.calls++
+	if mbs.batchErr != nil {
+		return nil, mbs.batchErr
+	}
+
+	results := make([]ghsearch.UserResult, len(usernames))
+	for i, uname := range usernames {
+		results[i].Username = uname
+		results[i].User, results[i].Err = mbs.User(ctx, uname)
+	}
+	return results, nil
+}